@@ -14,6 +14,7 @@ package errorutil
 
 import (
 	"bytes"
+	"fmt"
 )
 
 /*
@@ -38,20 +39,38 @@ func AssertTrue(condition bool, errString string) {
 CompositeError can collect multiple errors in a single error object.
 */
 type CompositeError struct {
-	Errors []error
+	Errors   []error
+	Overflow int // Number of errors which were not stored because the cap was reached
+	max      int // Maximum number of stored errors (0 for no limit)
 }
 
 /*
 NewCompositeError creates a new composite error object.
 */
 func NewCompositeError() *CompositeError {
-	return &CompositeError{make([]error, 0)}
+	return &CompositeError{make([]error, 0), 0, 0}
+}
+
+/*
+NewCappedCompositeError creates a new composite error object which stores at
+most max errors. Further errors are only counted. A max of 0 or less means
+no limit.
+*/
+func NewCappedCompositeError(max int) *CompositeError {
+	if max < 0 {
+		max = 0
+	}
+	return &CompositeError{make([]error, 0), 0, max}
 }
 
 /*
 Add adds an error.
 */
 func (ce *CompositeError) Add(e error) {
+	if ce.max > 0 && len(ce.Errors) >= ce.max {
+		ce.Overflow++
+		return
+	}
 	ce.Errors = append(ce.Errors, e)
 }
 
@@ -59,7 +78,7 @@ func (ce *CompositeError) Add(e error) {
 HasErrors returns true if any error have been collected.
 */
 func (ce *CompositeError) HasErrors() bool {
-	return len(ce.Errors) > 0
+	return len(ce.Errors) > 0 || ce.Overflow > 0
 }
 
 /*
@@ -73,5 +92,8 @@ func (ce *CompositeError) Error() string {
 			buf.WriteString("; ")
 		}
 	}
+	if ce.Overflow > 0 {
+		buf.WriteString(fmt.Sprintf(" (and %v more)", ce.Overflow))
+	}
 	return buf.String()
 }
@@ -11,6 +11,7 @@ package errorutil
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -62,3 +63,51 @@ func TestCompositeError(t *testing.T) {
 		t.Error("Unexpected output:", ce.Error())
 	}
 }
+
+func TestCappedCompositeError(t *testing.T) {
+
+	ce := NewCappedCompositeError(2)
+
+	if ce.HasErrors() || ce.Error() != "" {
+		t.Error("Unexpected result:", ce.Error())
+		return
+	}
+
+	for i := 1; i <= 5; i++ {
+		ce.Add(fmt.Errorf("test%v", i))
+	}
+
+	if !ce.HasErrors() || len(ce.Errors) != 2 || ce.Overflow != 3 {
+		t.Error("Unexpected result:", ce.Errors, ce.Overflow)
+		return
+	}
+
+	if ce.Error() != "test1; test2 (and 3 more)" {
+		t.Error("Unexpected output:", ce.Error())
+		return
+	}
+
+	// No overflow below the cap
+
+	ce = NewCappedCompositeError(2)
+	ce.Add(errors.New("test1"))
+	ce.Add(errors.New("test2"))
+
+	if ce.Overflow != 0 || ce.Error() != "test1; test2" {
+		t.Error("Unexpected output:", ce.Error())
+		return
+	}
+
+	// A cap of 0 means no limit
+
+	ce = NewCappedCompositeError(0)
+
+	for i := 1; i <= 3; i++ {
+		ce.Add(fmt.Errorf("test%v", i))
+	}
+
+	if ce.Overflow != 0 || ce.Error() != "test1; test2; test3" {
+		t.Error("Unexpected output:", ce.Error())
+		return
+	}
+}
@@ -0,0 +1,596 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/krotik/common/stringutil"
+)
+
+/*
+DeprecationInfo describes a field which has a @deprecated directive.
+*/
+type DeprecationInfo struct {
+	Path   string   // Path of response keys to the field (e.g. user.name)
+	Field  *ASTNode // Field node
+	Reason string   // Reason for the deprecation (empty if no reason was given)
+}
+
+/*
+String returns a string representation of this deprecation info.
+*/
+func (di DeprecationInfo) String() string {
+	return fmt.Sprintf("%v (%v)", di.Path, di.Reason)
+}
+
+/*
+DeprecatedFields returns all fields of a given AST which have a @deprecated
+directive.
+*/
+func DeprecatedFields(doc *ASTNode) []DeprecationInfo {
+	var ret []DeprecationInfo
+	var visit func(n *ASTNode, path string)
+
+	visit = func(n *ASTNode, path string) {
+
+		if n.Name == NodeField {
+
+			if path != "" {
+				path += "."
+			}
+			path += fieldResponseKey(n)
+
+			if _, ok := n.Directive("deprecated"); ok {
+				var reason string
+
+				if v, ok := n.DirectiveArg("deprecated", "reason"); ok {
+					reason = fmt.Sprint(v)
+				}
+
+				ret = append(ret, DeprecationInfo{path, n, reason})
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child, path)
+		}
+	}
+
+	visit(doc, "")
+
+	return ret
+}
+
+/*
+InferVariableTypes guesses the types of all variables in a given operation.
+Types are taken from the variable definitions if available. Otherwise, the
+type is inferred from literal values which are used in the same argument
+context (i.e. same argument of a field or directive with the same name and
+same position within list and object values). Mixed Int and Float literals
+result in Float, enum literals result in Enum. Variables whose type cannot be
+inferred are reported as Unknown.
+*/
+func InferVariableTypes(op *ASTNode) map[string]string {
+	ret := make(map[string]string)
+	literalTypes := make(map[string]string)
+	variableContexts := make(map[string][]string)
+	var visit func(n *ASTNode, owner string, ctx string)
+
+	addType := func(ctx string, t string) {
+		if old := literalTypes[ctx]; old == "" {
+			literalTypes[ctx] = t
+		} else if (old == "Int" && t == "Float") || (old == "Float" && t == "Int") {
+			literalTypes[ctx] = "Float"
+		}
+	}
+
+	visit = func(n *ASTNode, owner string, ctx string) {
+
+		switch n.Name {
+
+		case NodeVariableDefinition:
+
+			// Use the defined type if it is a simple named type
+
+			if len(n.Children) > 1 && n.Children[1].Name == NodeType &&
+				len(n.Children[1].Children) == 0 {
+				ret[n.Children[0].Token.Val] = n.Children[1].Token.Val
+			} else if _, ok := ret[n.Children[0].Token.Val]; !ok {
+				ret[n.Children[0].Token.Val] = ""
+			}
+			return
+
+		case NodeField:
+			owner = n.Children[0].Token.Val
+			if len(n.Children) > 1 && n.Children[0].Name == NodeAlias {
+				owner = n.Children[1].Token.Val
+			}
+
+		case NodeDirective:
+			owner = "@" + n.Children[0].Token.Val
+
+		case NodeArgument:
+			ctx = fmt.Sprintf("%v(%v)", owner, n.Children[0].Token.Val)
+
+		case NodeObjectField:
+			ctx = fmt.Sprintf("%v.%v", ctx, n.Token.Val)
+
+		case NodeValue:
+			switch {
+			case n.Token.ID == TokenIntValue:
+				addType(ctx, "Int")
+			case n.Token.ID == TokenFloatValue:
+				addType(ctx, "Float")
+			case n.Token.ID == TokenStringValue:
+				addType(ctx, "String")
+			case n.Token.Val == "true" || n.Token.Val == "false":
+				addType(ctx, "Boolean")
+			}
+
+		case NodeEnumValue:
+			if ctx != "" {
+				addType(ctx, "Enum")
+			}
+
+		case NodeVariable:
+			if ctx != "" {
+				variableContexts[n.Token.Val] = append(variableContexts[n.Token.Val], ctx)
+				if _, ok := ret[n.Token.Val]; !ok {
+					ret[n.Token.Val] = ""
+				}
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child, owner, ctx)
+		}
+	}
+
+	visit(op, "", "")
+
+	for name, t := range ret {
+
+		for _, ctx := range variableContexts[name] {
+			if t != "" {
+				break
+			}
+			t = literalTypes[ctx]
+		}
+
+		if t == "" {
+			t = "Unknown"
+		}
+
+		ret[name] = t
+	}
+
+	return ret
+}
+
+/*
+ValidateEnumValues checks all enum values of a given AST against a set of
+allowed values. The allowed map contains for an argument name all valid enum
+values. Enum values of arguments which are not in the map are not checked.
+Enum values in a list value are checked against the list's argument. Returns
+an ErrInvalidEnumValue error with the position of the first invalid value.
+*/
+func ValidateEnumValues(doc *ASTNode, allowed map[string][]string) error {
+	var visit func(n *ASTNode, argName string) error
+
+	visit = func(n *ASTNode, argName string) error {
+
+		if n.Name == NodeArgument {
+			argName = n.Children[0].Token.Val
+		} else if n.Name == NodeObjectValue {
+			argName = "" // Fields of input objects are not arguments
+		} else if n.Name == NodeEnumValue {
+
+			if values, ok := allowed[argName]; ok && stringutil.IndexOf(n.Token.Val, values) == -1 {
+				return newASTError(ErrInvalidEnumValue, fmt.Sprintf("%v for argument %v (allowed: %v)",
+					n.Token.Val, argName, strings.Join(values, ", ")), n)
+			}
+		}
+
+		for _, child := range n.Children {
+			if err := visit(child, argName); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return visit(doc, "")
+}
+
+/*
+DocumentKinds counts the operations of a given document by their operation
+type (query, mutation or subscription). Query shorthands are counted as
+queries.
+*/
+func DocumentKinds(doc *ASTNode) map[string]int {
+	ret := make(map[string]int)
+
+	for _, ed := range doc.Children {
+
+		if len(ed.Children) == 0 || ed.Children[0].Name != NodeOperationDefinition {
+			continue
+		}
+
+		kind := "query"
+
+		if od := ed.Children[0]; od.Children[0].Name == NodeOperationType {
+			kind = od.Children[0].Token.Val
+		}
+
+		ret[kind]++
+	}
+
+	return ret
+}
+
+/*
+FragmentUsage counts how often each fragment is spread in a given document.
+Spreads inside fragment definitions are counted as well. Fragments which are
+defined but never spread have a count of 0.
+*/
+func FragmentUsage(doc *ASTNode) map[string]int {
+	ret := make(map[string]int)
+
+	for _, fd := range doc.FindAll(NodeFragmentDefinition) {
+		ret[fd.Children[0].Token.Val] = 0
+	}
+
+	for _, spread := range doc.FindAll(NodeFragmentSpread) {
+		ret[spread.Token.Val]++
+	}
+
+	return ret
+}
+
+/*
+RootSelectionSet returns the selection set of the only operation of a given
+document. Returns an error if the document contains no operation or more than
+one operation.
+*/
+func RootSelectionSet(doc *ASTNode) (*ASTNode, error) {
+	var operation *ASTNode
+
+	for _, ed := range doc.Children {
+
+		if len(ed.Children) == 0 || ed.Children[0].Name != NodeOperationDefinition {
+			continue
+		}
+
+		if operation != nil {
+			return nil, fmt.Errorf("Operation name required for documents with multiple operations")
+		}
+
+		operation = ed.Children[0]
+	}
+
+	if operation == nil {
+		return nil, fmt.Errorf("Document contains no operation")
+	}
+
+	return operation.Children[len(operation.Children)-1], nil
+}
+
+/*
+HasMutation checks if a given document contains a mutation operation.
+*/
+func HasMutation(doc *ASTNode) bool {
+	return DocumentKinds(doc)["mutation"] > 0
+}
+
+/*
+HasSubscription checks if a given document contains a subscription operation.
+*/
+func HasSubscription(doc *ASTNode) bool {
+	return DocumentKinds(doc)["subscription"] > 0
+}
+
+/*
+Matches checks if a given document has the same shape as any of the given
+allowlisted documents. Documents have the same shape if they are equal after
+ignoring literal argument values and whitespace (see ShapeFingerprint).
+Returns if the document matches and the index of the first matching
+allowlist entry (-1 if there is no match).
+*/
+func Matches(doc *ASTNode, allowlist []*ASTNode) (bool, int) {
+
+	fp := ShapeFingerprint(doc)
+
+	for i, entry := range allowlist {
+		if ShapeFingerprint(entry) == fp {
+			return true, i
+		}
+	}
+
+	return false, -1
+}
+
+/*
+CoerceValue coerces a given value node to a given scalar type (Int, Float,
+String, Boolean or ID) following the input coercion rules of GraphQL. Int
+values are returned as int, Float values as float64, String and ID values
+as string and Boolean values as bool. Int literals are accepted for Float
+and ID; null is accepted for all types and returned as nil. All other
+combinations (including variables, lists and objects) produce an
+ErrCannotCoerce error.
+*/
+func CoerceValue(node *ASTNode, targetType string) (interface{}, error) {
+	var ret interface{}
+	var err error
+
+	val := node.Token.Val
+	id := node.Token.ID
+
+	if (node.Name != NodeValue && node.Name != NodeDefaultValue) || id == TokenPunctuator {
+		return nil, newASTError(ErrCannotCoerce, fmt.Sprintf("%v to %v", node.Name, targetType), node)
+	}
+
+	if id == TokenName && val == "null" {
+		return nil, nil
+	}
+
+	switch {
+
+	case targetType == "Int" && id == TokenIntValue:
+		var i int64
+
+		// Int values are 32 bit signed integers
+
+		if i, err = strconv.ParseInt(val, 10, 32); err == nil {
+			ret = int(i)
+		}
+
+	case targetType == "Float" && (id == TokenIntValue || id == TokenFloatValue):
+		var f float64
+
+		if f, err = strconv.ParseFloat(val, 64); err == nil && !math.IsInf(f, 0) {
+			ret = f
+		}
+
+	case targetType == "String" && id == TokenStringValue:
+		ret = val
+
+	case targetType == "ID" && (id == TokenStringValue || id == TokenIntValue):
+		ret = val
+
+	case targetType == "Boolean" && id == TokenName && (val == "true" || val == "false"):
+		ret = val == "true"
+	}
+
+	if ret == nil {
+		err = newASTError(ErrCannotCoerce, fmt.Sprintf("%v to %v", node.Token, targetType), node)
+	}
+
+	return ret, err
+}
+
+/*
+UsedVariables returns the names of all variables which are used in a given
+operation in the order of their first use. Fragment spreads are followed if
+the operation is part of a document (i.e. its parent nodes are set).
+*/
+func UsedVariables(op *ASTNode) []string {
+	var ret []string
+
+	fragments := make(map[string]*ASTNode)
+
+	if op.Parent != nil && op.Parent.Parent != nil {
+		fragments = FragmentMap(op.Parent.Parent)
+	}
+
+	for _, v := range usedVariables(op, fragments) {
+		ret = append(ret, v.Token.Val)
+	}
+
+	return ret
+}
+
+/*
+usedVariables returns the first use of all variables which are used in a
+given operation. Fragment spreads are followed using the given fragment
+definitions.
+*/
+func usedVariables(op *ASTNode, fragments map[string]*ASTNode) []*ASTNode {
+	var ret []*ASTNode
+	var names []string
+	var visit func(n *ASTNode)
+
+	visited := make(map[string]bool)
+
+	visit = func(n *ASTNode) {
+
+		switch n.Name {
+
+		case NodeVariableDefinitions:
+			return
+
+		case NodeVariable:
+			if stringutil.IndexOf(n.Token.Val, names) == -1 {
+				names = append(names, n.Token.Val)
+				ret = append(ret, n)
+			}
+
+		case NodeFragmentSpread:
+			if fd, ok := fragments[n.Token.Val]; ok && !visited[n.Token.Val] {
+				visited[n.Token.Val] = true
+				visit(fd)
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+
+	visit(op)
+
+	return ret
+}
+
+/*
+ValidateVariableDefinitions checks the variable definitions of a given
+operation. Returns an ErrDuplicateVariable error if a variable is defined
+more than once.
+*/
+func ValidateVariableDefinitions(op *ASTNode) error {
+	return validateVariableDefinitions(op, false)
+}
+
+/*
+ValidateVariableDefinitionsStrict checks the variable definitions of a given
+operation like ValidateVariableDefinitions. Additionally returns an
+ErrUnusedVariable error if a defined variable is not used (see UsedVariables).
+*/
+func ValidateVariableDefinitionsStrict(op *ASTNode) error {
+	return validateVariableDefinitions(op, true)
+}
+
+/*
+validateVariableDefinitions checks the variable definitions of a given
+operation.
+*/
+func validateVariableDefinitions(op *ASTNode, strict bool) error {
+	var used []string
+
+	if strict {
+		used = UsedVariables(op)
+	}
+
+	if errs := variableDefinitionErrors(op, used, strict); len(errs) > 0 {
+		return errs[0]
+	}
+
+	return nil
+}
+
+/*
+variableDefinitionErrors returns all errors in the variable definitions of a
+given operation. Unused variables are only reported if strict is set.
+*/
+func variableDefinitionErrors(op *ASTNode, used []string, strict bool) []error {
+	var errs []error
+	var names []string
+
+	for _, vds := range op.FindAll(NodeVariableDefinitions) {
+		for _, vd := range vds.Children {
+			v := vd.Children[0]
+
+			if stringutil.IndexOf(v.Token.Val, names) != -1 {
+				errs = append(errs, newASTError(ErrDuplicateVariable, "$"+v.Token.Val, v))
+				continue
+			}
+
+			if strict && stringutil.IndexOf(v.Token.Val, used) == -1 {
+				errs = append(errs, newASTError(ErrUnusedVariable, "$"+v.Token.Val, v))
+			}
+
+			names = append(names, v.Token.Val)
+		}
+	}
+
+	return errs
+}
+
+/*
+EnforceFieldAllowlist checks that all fields which are selected by a given
+document are allowed. The path of a field consists of the names (not the
+aliases) of all fields from the root of its operation joined by dots (e.g.
+user.friends.name). Only the paths of fields without a selection set are
+checked. The allowed paths can contain glob wildcards (e.g. user.*). Returns
+an ErrFieldNotAllowed error with the path of the first field which is not
+allowed.
+*/
+func EnforceFieldAllowlist(doc *ASTNode, allowed []string) error {
+	var patterns []*regexp.Regexp
+	var check func(selectionSet *ASTNode, prefix string, stack []string) error
+
+	for _, glob := range allowed {
+		re, err := stringutil.GlobToRegex(glob)
+		if err != nil {
+			return err
+		}
+
+		patterns = append(patterns, regexp.MustCompile("^(?:"+re+")$"))
+	}
+
+	e := &executor{FragmentMap(doc), nil}
+
+	check = func(selectionSet *ASTNode, prefix string, stack []string) error {
+
+		for _, selection := range selectionSet.Children {
+			var err error
+
+			switch selection.Name {
+
+			case NodeField:
+				path := prefix
+
+				for _, child := range selection.Children {
+					if child.Name == NodeName {
+						path += child.Token.Val
+					}
+				}
+
+				if ss := selection.Children[len(selection.Children)-1]; ss.Name == NodeSelectionSet {
+					err = check(ss, path+".", stack)
+
+				} else {
+					err = newASTError(ErrFieldNotAllowed, path, selection)
+
+					for _, p := range patterns {
+						if p.MatchString(path) {
+							err = nil
+							break
+						}
+					}
+				}
+
+			case NodeInlineFragment:
+				err = check(selection.Children[len(selection.Children)-1], prefix, stack)
+
+			case NodeFragmentSpread:
+				var fd *ASTNode
+
+				if fd, err = e.fragment(selection, stack); err == nil {
+					err = check(fd.Children[len(fd.Children)-1], prefix,
+						append(stack[:len(stack):len(stack)], selection.Token.Val))
+				}
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, ed := range doc.Children {
+		if len(ed.Children) > 0 && ed.Children[0].Name == NodeOperationDefinition {
+			op := ed.Children[0]
+
+			if err := check(op.Children[len(op.Children)-1], "", nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
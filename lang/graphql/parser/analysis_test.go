@@ -0,0 +1,500 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeprecatedFields(t *testing.T) {
+
+	ast, err := Parse("mytest", `{
+  user {
+    id
+    name @deprecated(reason: "Use fullName")
+    pic : avatar @deprecated
+  }
+  oldRoot @include(if: true) @deprecated
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res := DeprecatedFields(ast)
+
+	if fmt.Sprint(res) != "[user.name (Use fullName) user.pic () oldRoot ()]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res[1].Reason != "" || res[1].Field.Children[1].Token.Val != "avatar" {
+		t.Error("Unexpected result:", res[1])
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { id } }`)
+
+	if res := DeprecatedFields(ast); len(res) != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestInferVariableTypes(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query q($name: String) {
+  a : user(id: 1, name: $name) { id }
+  b : user(id: $id) {
+    friends(first: $first, filter: {score: 1.5, mode: FAST}) @include(if: $show) { id }
+  }
+  c : user(id: 2.5) {
+    friends(first: 10, filter: {score: $score, mode: $mode}) { id }
+  }
+  d : user(tags: ["a", $tag], other: $other) { id }
+  x: find(flag: false) @include(if: true)
+  y: find(flag: $flag)
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res := InferVariableTypes(ast)
+
+	if fmt.Sprint(res) != "map[first:Int flag:Boolean id:Float mode:Enum name:String "+
+		"other:Unknown score:Float show:Boolean tag:String]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user(id: $id) { name } }`)
+
+	if res := InferVariableTypes(ast); fmt.Sprint(res) != "map[id:Unknown]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ a : user(id: 1) { name } b : user(id: $id) { name } }`)
+
+	if res := InferVariableTypes(ast); fmt.Sprint(res) != "map[id:Int]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestValidateEnumValues(t *testing.T) {
+	allowed := map[string][]string{
+		"role":  {"ADMIN", "USER"},
+		"order": {"ASC", "DESC"},
+	}
+
+	ast, err := Parse("mytest", `{
+  user(role: ADMIN, kind: ANYTHING) {
+    friends(order: DESC, roles: [USER, FOO], filter: {role: GUEST}) { id }
+  }
+  other(role: [USER, ADMIN], order: ASC)
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := ValidateEnumValues(ast, allowed); err != nil {
+		t.Error(err)
+		return
+	}
+
+	ast, err = Parse("mytest", `{
+  user(role: ADMIN) {
+    friends(order: UP, role: GUEST) { id }
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = ValidateEnumValues(ast, allowed)
+	if err == nil || err.Error() != "Parse error in AST: Invalid enum value "+
+		"(UP for argument order (allowed: ASC, DESC)) (Line:3 Pos:21)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err.(*Error).Type != ErrInvalidEnumValue {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user(role: [ADMIN, ROOT]) { id } }`)
+
+	if err := ValidateEnumValues(ast, allowed); err == nil || err.Error() != "Parse error in AST: Invalid enum value "+
+		"(ROOT for argument role (allowed: ADMIN, USER)) (Line:1 Pos:22)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestFragmentUsage(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query a { user { ...userFields } }
+query b { admin { ...userFields ...adminFields } }
+fragment userFields on User { id ...nameFields }
+fragment nameFields on User { name }
+fragment adminFields on User { ...nameFields }
+fragment unused on User { id }
+`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := FragmentUsage(ast); fmt.Sprint(res) != "map[adminFields:1 nameFields:2 unused:0 userFields:2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { id } }`)
+
+	if res := FragmentUsage(ast); len(res) != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestDocumentKinds(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query a { user { id } }
+mutation b { like(id: 1) { id } }
+query c { user { name } }
+fragment f on User { id }
+mutation d { unlike(id: 1) { id } }
+`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := DocumentKinds(ast); fmt.Sprint(res) != "map[mutation:2 query:2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if !HasMutation(ast) || HasSubscription(ast) {
+		t.Error("Unexpected result")
+		return
+	}
+
+	ast, _ = Parse("mytest", `subscription s { news { text } }`)
+
+	if res := DocumentKinds(ast); fmt.Sprint(res) != "map[subscription:1]" || HasMutation(ast) || !HasSubscription(ast) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { id } } fragment f on User { id }`)
+
+	if res := DocumentKinds(ast); fmt.Sprint(res) != "map[query:1]" || HasMutation(ast) || HasSubscription(ast) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestRootSelectionSet(t *testing.T) {
+
+	for _, q := range []string{
+		`{ user { name } }`,
+		`query getUser($id: ID) @cached { user(id: $id) { name } } fragment f on User { id }`,
+		`fragment f on User { id } subscription s { user { name } }`,
+	} {
+		ast, err := Parse("mytest", q)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		ss, err := RootSelectionSet(ast)
+		if err != nil || ss.Name != NodeSelectionSet || ss.Parent.Name != NodeOperationDefinition ||
+			ss.Children[0].Children[0].Token.Val != "user" {
+			t.Error("Unexpected result:", ss, err)
+			return
+		}
+	}
+
+	ast, _ := Parse("mytest", `query a { user { name } } query b { user { id } }`)
+
+	if ss, err := RootSelectionSet(ast); ss != nil || err == nil ||
+		err.Error() != "Operation name required for documents with multiple operations" {
+		t.Error("Unexpected result:", ss, err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `fragment f on User { id }`)
+
+	if ss, err := RootSelectionSet(ast); ss != nil || err == nil || err.Error() != "Document contains no operation" {
+		t.Error("Unexpected result:", ss, err)
+		return
+	}
+}
+
+func TestMatches(t *testing.T) {
+	var allowlist []*ASTNode
+
+	for _, q := range []string{
+		`{ user(id: 1) { name } }`,
+		`query getUser($id: ID) { user(id: $id, type: ADMIN) { name friends(first: 10) { name } } }`,
+	} {
+		ast, err := Parse("allowlist", q)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		allowlist = append(allowlist, ast)
+	}
+
+	ast, _ := Parse("mytest", `query getUser($id: ID) {
+  user(id: $id, type: USER) {
+    name
+    friends(first: 99) { name }
+  }
+}`)
+
+	if ok, i := Matches(ast, allowlist); !ok || i != 1 {
+		t.Error("Unexpected result:", ok, i)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user(id: "foo") { name } }`)
+
+	if ok, i := Matches(ast, allowlist); !ok || i != 0 {
+		t.Error("Unexpected result:", ok, i)
+		return
+	}
+
+	// Different shapes do not match
+
+	for _, q := range []string{
+		`{ user(id: 1) { name email } }`,
+		`{ user(name: 1) { name } }`,
+		`{ user(id: $id) { name } }`,
+	} {
+		ast, _ = Parse("mytest", q)
+
+		if ok, i := Matches(ast, allowlist); ok || i != -1 {
+			t.Error("Unexpected result:", q, ok, i)
+			return
+		}
+	}
+
+	if ok, i := Matches(ast, nil); ok || i != -1 {
+		t.Error("Unexpected result:", ok, i)
+		return
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+
+	ast, err := Parse("mytest", `query q($v: Int = 5) {
+  f(i: 42, f: 1.5, s: "foo", b: true, n: null, e: RED, v: $v, l: [1], o: {a: 1}, big: 3000000000, sid: "x1")
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	args := make(map[string]*ASTNode)
+
+	for _, arg := range ast.FindAll(NodeArgument) {
+		args[arg.Children[0].Token.Val] = arg.Children[1]
+	}
+
+	coerce := func(arg string, targetType string) string {
+		res, err := CoerceValue(args[arg], targetType)
+		if err != nil {
+			return err.Error()
+		}
+		return fmt.Sprintf("%T:%v", res, res)
+	}
+
+	// Allowed coercions
+
+	for _, test := range [][]string{
+		{"i", "Int", "int:42"},
+		{"i", "Float", "float64:42"},
+		{"i", "ID", "string:42"},
+		{"f", "Float", "float64:1.5"},
+		{"s", "String", "string:foo"},
+		{"sid", "ID", "string:x1"},
+		{"b", "Boolean", "bool:true"},
+		{"n", "Int", "<nil>:<nil>"},
+		{"n", "String", "<nil>:<nil>"},
+	} {
+		if res := coerce(test[0], test[1]); res != test[2] {
+			t.Error("Unexpected result:", test, res)
+			return
+		}
+	}
+
+	// Disallowed coercions
+
+	for _, test := range [][]string{
+		{"f", "Int", "Parse error in AST: Cannot coerce value (flt(1.5) to Int) (Line:2 Pos:16)"},
+		{"big", "Int", "Parse error in AST: Cannot coerce value (int(3000000000) to Int) (Line:2 Pos:88)"},
+		{"s", "Int", `Parse error in AST: Cannot coerce value ("foo" to Int) (Line:2 Pos:24)`},
+		{"i", "String", "Parse error in AST: Cannot coerce value (int(42) to String) (Line:2 Pos:9)"},
+		{"b", "String", "Parse error in AST: Cannot coerce value (<true> to String) (Line:2 Pos:34)"},
+		{"f", "ID", "Parse error in AST: Cannot coerce value (flt(1.5) to ID) (Line:2 Pos:16)"},
+		{"s", "Boolean", `Parse error in AST: Cannot coerce value ("foo" to Boolean) (Line:2 Pos:24)`},
+		{"e", "String", "Parse error in AST: Cannot coerce value (EnumValue to String) (Line:2 Pos:52)"},
+		{"v", "Int", "Parse error in AST: Cannot coerce value (Variable to Int) (Line:2 Pos:61)"},
+		{"l", "Int", "Parse error in AST: Cannot coerce value (ListValue to Int) (Line:2 Pos:67)"},
+		{"o", "Int", "Parse error in AST: Cannot coerce value (ObjectValue to Int) (Line:2 Pos:75)"},
+		{"i", "Foo", "Parse error in AST: Cannot coerce value (int(42) to Foo) (Line:2 Pos:9)"},
+	} {
+		if res := coerce(test[0], test[1]); res != test[2] {
+			t.Error("Unexpected result:", test, res)
+			return
+		}
+	}
+
+	if res, err := CoerceValue(ast.FindFirst(NodeDefaultValue), "Int"); err != nil || res != 5 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestValidateVariableDefinitions(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query q($id: ID, $first: Int = 10, $unused: String, $skip: Boolean) {
+  user(id: $id) @skip(if: $skip) {
+    ...friends
+  }
+}
+fragment friends on User { friends(first: $first) { ...more } }
+fragment more on User { name(id: $id) ...friends }
+`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	op := ast.FindFirst(NodeOperationDefinition)
+
+	if res := UsedVariables(op); fmt.Sprint(res) != "[id skip first]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Fragments cannot be followed without a document
+
+	if res := UsedVariables(op.Clone()); fmt.Sprint(res) != "[id skip]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if err := ValidateVariableDefinitions(op); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := ValidateVariableDefinitionsStrict(op); err == nil ||
+		err.Error() != "Parse error in AST: Unused variable ($unused) (Line:2 Pos:38)" ||
+		err.(*Error).Type != ErrUnusedVariable {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `query q($id: ID, $name: String, $id: Int) { user(id: $id, name: $name) { name } }`)
+	op = ast.FindFirst(NodeOperationDefinition)
+
+	for _, validate := range []func(*ASTNode) error{ValidateVariableDefinitions, ValidateVariableDefinitionsStrict} {
+		if err := validate(op); err == nil ||
+			err.Error() != "Parse error in AST: Duplicate variable ($id) (Line:1 Pos:34)" ||
+			err.(*Error).Type != ErrDuplicateVariable {
+			t.Error("Unexpected result:", err)
+			return
+		}
+	}
+
+	ast, _ = Parse("mytest", `{ user { name } }`)
+
+	if err := ValidateVariableDefinitionsStrict(ast.FindFirst(NodeOperationDefinition)); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestEnforceFieldAllowlist(t *testing.T) {
+	allowed := []string{"user.*", "version", "admin.name"}
+
+	ast, err := Parse("mytest", `{
+  user(id: 1) {
+    name
+    friends { name }
+    ...userDetails
+  }
+  version
+  admin { name }
+}
+fragment userDetails on User { email }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := EnforceFieldAllowlist(ast, allowed); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, q := range []string{
+		`{ user { name } admin { name secrets } }`,
+		`{ version admin { ...secret } } fragment secret on Admin { secrets }`,
+		`{ admin { ... on Admin { secrets } } }`,
+		`{ admin { name: secrets } }`,
+		`query a { version } mutation b { admin { secrets } }`,
+	} {
+		ast, _ := Parse("mytest", q)
+
+		if err := EnforceFieldAllowlist(ast, allowed); err == nil || err.(*Error).Type != ErrFieldNotAllowed ||
+			err.(*Error).Detail != "admin.secrets" {
+			t.Error("Unexpected result:", i, err)
+			return
+		}
+	}
+
+	ast, _ = Parse("mytest", `{ admin { name } foo }`)
+
+	if err := EnforceFieldAllowlist(ast, allowed); err == nil ||
+		err.Error() != "Parse error in AST: Field not allowed (foo) (Line:1 Pos:18)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := EnforceFieldAllowlist(ast, []string{"[a"}); err == nil ||
+		err.Error() != "Unclosed character class at 2 of [a" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { ...a } } fragment a on User { ...a }`)
+
+	if err := EnforceFieldAllowlist(ast, allowed); err == nil ||
+		err.Error() != "Parse error in AST: Cyclic fragment reference (a -> a) (Line:1 Pos:43)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
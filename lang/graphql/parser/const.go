@@ -40,6 +40,10 @@ with runtime components which can be used to interpret the parsed query.
 */
 package parser
 
+import (
+	"fmt"
+)
+
 /*
 LexTokenID represents a unique lexer token ID
 */
@@ -100,6 +104,42 @@ const (
 	TokenGeneral
 )
 
+/*
+tokenIDNames maps lexer token IDs to the names of their constants.
+*/
+var tokenIDNames = map[LexTokenID]string{
+	TokenError:       "TokenError",
+	TokenEOF:         "TokenEOF",
+	TokenPunctuator:  "TokenPunctuator",
+	TokenName:        "TokenName",
+	TokenIntValue:    "TokenIntValue",
+	TokenFloatValue:  "TokenFloatValue",
+	TokenStringValue: "TokenStringValue",
+	TokenGeneral:     "TokenGeneral",
+}
+
+/*
+String returns the name of the constant of a lexer token ID.
+*/
+func (id LexTokenID) String() string {
+	if name, ok := tokenIDNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("LexTokenID(%d)", int(id))
+}
+
+/*
+TokenIDByName returns the lexer token ID for a given constant name.
+*/
+func TokenIDByName(name string) (LexTokenID, bool) {
+	for id, idName := range tokenIDNames {
+		if idName == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
 /*
 Available parser AST node types
 */
@@ -0,0 +1,164 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+
+	"github.com/krotik/common/stringutil"
+)
+
+/*
+Kinds of AST differences
+*/
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+/*
+ASTDiff models a difference between two ASTs.
+*/
+type ASTDiff struct {
+	Path string   // Path to the node which is different
+	Kind string   // Kind of difference (added, removed or changed)
+	Old  *ASTNode // Node in the old AST (nil if the node was added)
+	New  *ASTNode // Node in the new AST (nil if the node was removed)
+}
+
+/*
+String returns a string representation of this difference.
+*/
+func (d ASTDiff) String() string {
+
+	if d.Kind == DiffChanged {
+		return fmt.Sprintf("%v %v: %v -> %v", d.Kind, d.Path, d.Old.Token.Val, d.New.Token.Val)
+	}
+
+	return fmt.Sprintf("%v %v", d.Kind, d.Path)
+}
+
+/*
+DiffAST determines the differences between two ASTs. Child nodes are matched
+by their identity (e.g. fields by their response key and arguments by their
+name) so only the minimal set of added, removed and changed nodes is reported.
+*/
+func DiffAST(a, b *ASTNode) []ASTDiff {
+	return diffNodes(a, b, diffPathSegment(a))
+}
+
+/*
+diffNodes compares two matching nodes and their children.
+*/
+func diffNodes(a, b *ASTNode, path string) []ASTDiff {
+	var ret []ASTDiff
+
+	if stringutil.IndexOf(a.Name, ValueNodes) != -1 && a.Token.Val != b.Token.Val {
+		ret = append(ret, ASTDiff{path, DiffChanged, a, b})
+	}
+
+	// Align the children using a longest common subsequence of their keys
+
+	la, lb := len(a.Children), len(b.Children)
+	lcs := make([][]int, la+1)
+	for i := range lcs {
+		lcs[i] = make([]int, lb+1)
+	}
+
+	for i := la - 1; i >= 0; i-- {
+		for j := lb - 1; j >= 0; j-- {
+			if diffKey(a.Children[i]) == diffKey(b.Children[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+
+	for i < la || j < lb {
+		switch {
+		case i < la && j < lb && diffKey(a.Children[i]) == diffKey(b.Children[j]):
+			ret = append(ret, diffNodes(a.Children[i], b.Children[j],
+				path+"/"+diffPathSegment(a.Children[i]))...)
+			i++
+			j++
+
+		case i < la && (j == lb || lcs[i+1][j] >= lcs[i][j+1]):
+			ret = append(ret, ASTDiff{path + "/" + diffPathSegment(a.Children[i]),
+				DiffRemoved, a.Children[i], nil})
+			i++
+
+		default:
+			ret = append(ret, ASTDiff{path + "/" + diffPathSegment(b.Children[j]),
+				DiffAdded, nil, b.Children[j]})
+			j++
+		}
+	}
+
+	return ret
+}
+
+/*
+diffIdentity returns the identity of a node among its siblings (e.g. the
+response key of a field). Returns an empty string for nodes which are only
+identified by their kind.
+*/
+func diffIdentity(n *ASTNode) string {
+	var ret string
+
+	switch n.Name {
+
+	case NodeField:
+		ret = fieldResponseKey(n)
+
+	case NodeArgument, NodeDirective, NodeOperationDefinition, NodeFragmentDefinition:
+		for _, c := range n.Children {
+			if c.Name == NodeName || c.Name == NodeFragmentName {
+				ret = c.Token.Val
+				break
+			}
+		}
+
+	case NodeExecutableDefinition, NodeVariableDefinition:
+		if len(n.Children) > 0 {
+			ret = diffIdentity(n.Children[0])
+			if ret == "" && n.Children[0].Name == NodeVariable {
+				ret = n.Children[0].Token.Val
+			}
+		}
+
+	case NodeObjectField, NodeFragmentSpread, NodeTypeCondition:
+		ret = n.Token.Val
+	}
+
+	return ret
+}
+
+/*
+diffKey returns the key which is used to match sibling nodes.
+*/
+func diffKey(n *ASTNode) string {
+	return n.Name + ":" + diffIdentity(n)
+}
+
+/*
+diffPathSegment returns the path segment for a given node.
+*/
+func diffPathSegment(n *ASTNode) string {
+	if id := diffIdentity(n); id != "" {
+		return fmt.Sprintf("%v(%v)", n.Name, id)
+	}
+	return n.Name
+}
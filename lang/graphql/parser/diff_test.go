@@ -0,0 +1,83 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiffAST(t *testing.T) {
+
+	a, err := Parse("mytest", `query q {
+  user(id: 1, role: ADMIN) {
+    id
+    name
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	b, err := Parse("mytest", `query q {
+  user(id: 2, role: ADMIN) {
+    id
+    email
+    name
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	diff := DiffAST(a, b)
+
+	if res := fmt.Sprint(len(diff), diff); res != "2 ["+
+		"changed Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/Arguments/Argument(id)/Value: 1 -> 2 "+
+		"added Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/SelectionSet/Field(email)]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if diff[1].Old != nil || diff[1].New.Children[0].Token.Val != "email" {
+		t.Error("Unexpected result:", diff[1])
+		return
+	}
+
+	diff = DiffAST(b, a)
+
+	if res := fmt.Sprint(len(diff), diff); res != "2 ["+
+		"changed Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/Arguments/Argument(id)/Value: 2 -> 1 "+
+		"removed Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/SelectionSet/Field(email)]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if diff := DiffAST(a, a); len(diff) != 0 {
+		t.Error("Unexpected result:", diff)
+		return
+	}
+
+	// Changing the kind of a value is reported as removal and addition
+
+	a, _ = Parse("mytest", `query q($v: Int) { user(id: 1) { id } }`)
+	b, _ = Parse("mytest", `query q($v: Int) { user(id: $v) { me : id } }`)
+
+	if res := fmt.Sprint(DiffAST(a, b)); res != "["+
+		"removed Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/Arguments/Argument(id)/Value "+
+		"added Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/Arguments/Argument(id)/Variable "+
+		"removed Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/SelectionSet/Field(id) "+
+		"added Document/ExecutableDefinition(q)/OperationDefinition(q)/SelectionSet/Field(user)/SelectionSet/Field(me)]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -0,0 +1,338 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krotik/common/errorutil"
+	"github.com/krotik/common/stringutil"
+)
+
+/*
+Execute executes an operation of a given AST which was parsed with a
+RuntimeProvider. The operation is selected by its name - the name can be
+empty if the document contains only one operation.
+
+All runtime components of the operation are validated first. Validation
+errors are returned as a CompositeError. The fields of the operation are then
+evaluated by calling Eval of their runtime components. The map returned by
+Eval contains the entries which a field adds to the result of its selection
+set (usually a single entry with the response key of the field). The result
+of the selection set of a field is added under the field's response key.
+
+The given variables are used to evaluate @skip and @include directives.
+*/
+func Execute(doc *ASTNode, op string, vars map[string]interface{}) (map[string]interface{}, error) {
+	var operation *ASTNode
+
+	fragments := make(map[string]*ASTNode)
+
+	for _, ed := range doc.Children {
+
+		if len(ed.Children) == 0 {
+			continue
+		}
+
+		def := ed.Children[0]
+
+		if def.Name == NodeFragmentDefinition {
+			fragments[def.Children[0].Token.Val] = def
+
+		} else if def.Name == NodeOperationDefinition && (op == "" || operationName(def) == op) {
+
+			if operation != nil {
+				return nil, fmt.Errorf("Operation name required for documents with multiple operations")
+			}
+
+			operation = def
+		}
+	}
+
+	if operation == nil {
+		return nil, fmt.Errorf("Unknown operation: %v", op)
+	}
+
+	e := &executor{fragments, vars}
+
+	// Validate all runtime components
+
+	cerr := errorutil.NewCompositeError()
+
+	if err := e.validate(operation, nil, cerr); err != nil {
+		return nil, err
+	}
+
+	if cerr.HasErrors() {
+		return nil, cerr
+	}
+
+	res := make(map[string]interface{})
+
+	if err := e.executeSelectionSet(operation.Children[len(operation.Children)-1], res, nil); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+ShapeResponse builds a response object for a given operation from a flat map
+of values. The values are keyed by the path of their field which consists of
+the response keys (i.e. the alias or the name) of all fields from the root of
+the operation joined by dots (e.g. me.friend.name). The response object has
+the same structure as the selections of the operation and uses the response
+keys of the fields as keys. Missing values are null. Fragment spreads are
+followed if the operation is part of a document (i.e. its parent nodes are
+set). This can be used to mock resolvers in tests.
+*/
+func ShapeResponse(op *ASTNode, values map[string]interface{}) (map[string]interface{}, error) {
+	fragments := make(map[string]*ASTNode)
+
+	if op.Parent != nil && op.Parent.Parent != nil {
+		fragments = FragmentMap(op.Parent.Parent)
+	}
+
+	e := &executor{fragments, nil}
+	res := make(map[string]interface{})
+
+	if err := e.shapeSelectionSet(op.Children[len(op.Children)-1], "", values, res, nil); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+/*
+executor data structure
+*/
+type executor struct {
+	fragments map[string]*ASTNode    // Fragment definitions of the document
+	vars      map[string]interface{} // Variable values
+}
+
+/*
+validate calls Validate on all runtime components of a given AST. Fragment
+definitions are validated where they are used. The stack contains the names
+of all fragments which are currently being validated.
+*/
+func (e *executor) validate(node *ASTNode, stack []string, cerr *errorutil.CompositeError) error {
+
+	if node.Runtime != nil {
+		if err := node.Runtime.Validate(); err != nil {
+			cerr.Add(err)
+		}
+	}
+
+	if node.Name == NodeFragmentSpread {
+
+		fd, err := e.fragment(node, stack)
+		if err != nil {
+			return err
+		}
+
+		if err := e.validate(fd, append(stack[:len(stack):len(stack)], node.Token.Val), cerr); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := e.validate(child, stack, cerr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+executeSelectionSet evaluates all selections of a given selection set and
+stores the results in a given result object.
+*/
+func (e *executor) executeSelectionSet(selectionSet *ASTNode, res map[string]interface{}, stack []string) error {
+
+	for _, selection := range selectionSet.Children {
+		var err error
+
+		if !e.included(selection) {
+			continue
+		}
+
+		switch selection.Name {
+
+		case NodeField:
+			err = e.executeField(selection, res, stack)
+
+		case NodeInlineFragment:
+			err = e.executeSelectionSet(selection.Children[len(selection.Children)-1], res, stack)
+
+		case NodeFragmentSpread:
+			var fd *ASTNode
+
+			if fd, err = e.fragment(selection, stack); err == nil {
+				err = e.executeSelectionSet(fd.Children[len(fd.Children)-1], res,
+					append(stack[:len(stack):len(stack)], selection.Token.Val))
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+executeField evaluates a given field and stores its result in a given result
+object.
+*/
+func (e *executor) executeField(field *ASTNode, res map[string]interface{}, stack []string) error {
+
+	if field.Runtime == nil {
+		return fmt.Errorf("Field %v has no runtime component", fieldResponseKey(field))
+	}
+
+	val, err := field.Runtime.Eval()
+
+	if err == nil {
+
+		for k, v := range val {
+			res[k] = v
+		}
+
+		if selectionSet := field.Children[len(field.Children)-1]; selectionSet.Name == NodeSelectionSet {
+			fieldRes, ok := res[fieldResponseKey(field)].(map[string]interface{})
+			if !ok {
+				fieldRes = make(map[string]interface{})
+			}
+
+			if err = e.executeSelectionSet(selectionSet, fieldRes, stack); err == nil {
+				res[fieldResponseKey(field)] = fieldRes
+			}
+		}
+	}
+
+	return err
+}
+
+/*
+shapeSelectionSet adds the values of all selections of a given selection set
+to a given result object. The prefix is the path of the selection set.
+*/
+func (e *executor) shapeSelectionSet(selectionSet *ASTNode, prefix string, values map[string]interface{},
+	res map[string]interface{}, stack []string) error {
+
+	for _, selection := range selectionSet.Children {
+		var err error
+
+		if !e.included(selection) {
+			continue
+		}
+
+		switch selection.Name {
+
+		case NodeField:
+			key := fieldResponseKey(selection)
+
+			if ss := selection.Children[len(selection.Children)-1]; ss.Name == NodeSelectionSet {
+
+				// Fields with the same response key are merged
+
+				fieldRes, ok := res[key].(map[string]interface{})
+				if !ok {
+					fieldRes = make(map[string]interface{})
+				}
+
+				err = e.shapeSelectionSet(ss, prefix+key+".", values, fieldRes, stack)
+				res[key] = fieldRes
+
+			} else {
+				res[key] = values[prefix+key]
+			}
+
+		case NodeInlineFragment:
+			err = e.shapeSelectionSet(selection.Children[len(selection.Children)-1], prefix, values, res, stack)
+
+		case NodeFragmentSpread:
+			var fd *ASTNode
+
+			if fd, err = e.fragment(selection, stack); err == nil {
+				err = e.shapeSelectionSet(fd.Children[len(fd.Children)-1], prefix, values, res,
+					append(stack[:len(stack):len(stack)], selection.Token.Val))
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+fragment returns the fragment definition for a given fragment spread.
+*/
+func (e *executor) fragment(spread *ASTNode, stack []string) (*ASTNode, error) {
+	name := spread.Token.Val
+
+	if stringutil.IndexOf(name, stack) != -1 {
+		return nil, newASTError(ErrCyclicFragment, strings.Join(append(stack, name), " -> "), spread)
+	}
+
+	fd, ok := e.fragments[name]
+	if !ok {
+		return nil, newASTError(ErrUnknownFragment, name, spread)
+	}
+
+	return fd, nil
+}
+
+/*
+included checks the @skip and @include directives of a given selection.
+*/
+func (e *executor) included(selection *ASTNode) bool {
+
+	if skip, ok := e.directiveCondition(selection, "skip"); ok && skip {
+		return false
+	}
+
+	if include, ok := e.directiveCondition(selection, "include"); ok && !include {
+		return false
+	}
+
+	return true
+}
+
+/*
+directiveCondition returns the value of the if argument of a given directive.
+*/
+func (e *executor) directiveCondition(node *ASTNode, directiveName string) (bool, bool) {
+
+	if directive, ok := node.Directive(directiveName); ok {
+
+		for _, arg := range directive.FindAll(NodeArgument) {
+
+			if arg.Children[0].Token.Val == "if" {
+
+				if value := arg.Children[1]; value.Name == NodeVariable {
+					b, ok := e.vars[value.Token.Val].(bool)
+					return b, ok
+				}
+
+				return arg.Children[1].Token.Val == "true", true
+			}
+		}
+	}
+
+	return false, false
+}
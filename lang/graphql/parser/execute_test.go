@@ -0,0 +1,244 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/krotik/common/errorutil"
+)
+
+/*
+testExecRuntimeProvider provides runtime components which return canned values
+for fields.
+*/
+type testExecRuntimeProvider struct {
+	values  map[string]interface{} // Canned values for field names
+	invalid []string               // Field names which fail validation
+}
+
+/*
+Runtime returns a runtime component for a given ASTNode.
+*/
+func (p *testExecRuntimeProvider) Runtime(node *ASTNode) Runtime {
+	return &testExecRuntime{p, node}
+}
+
+/*
+testExecRuntime is a runtime which returns a canned value for a field.
+*/
+type testExecRuntime struct {
+	p    *testExecRuntimeProvider
+	node *ASTNode
+}
+
+/*
+Validate this runtime component and all its child components.
+*/
+func (r *testExecRuntime) Validate() error {
+
+	if r.node.Name == NodeField {
+		for _, name := range r.p.invalid {
+			if r.node.Children[0].Token.Val == name {
+				return fmt.Errorf("Invalid field %v", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+Eval evaluate this runtime component.
+*/
+func (r *testExecRuntime) Eval() (map[string]interface{}, error) {
+	var name string
+
+	for _, child := range r.node.Children {
+		if child.Name == NodeName {
+			name = child.Token.Val
+		}
+	}
+
+	if val, ok := r.p.values[name]; ok {
+		if err, ok := val.(error); ok {
+			return nil, err
+		}
+
+		return map[string]interface{}{fieldResponseKey(r.node): val}, nil
+	}
+
+	return nil, nil
+}
+
+func TestExecute(t *testing.T) {
+	rp := &testExecRuntimeProvider{map[string]interface{}{
+		"name":  "Alice",
+		"age":   42,
+		"email": "alice@example.com",
+	}, nil}
+
+	doc, err := ParseWithRuntime("mytest", `{ name age }`, rp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := Execute(doc, "", nil); err != nil || fmt.Sprint(res) != "map[age:42 name:Alice]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	doc, err = ParseWithRuntime("mytest", `
+query q($withMail: Boolean) {
+  user {
+    n : name
+    ...details
+    ... on User { email @include(if: $withMail) }
+  }
+  other: user @skip(if: true) { name }
+}
+fragment details on User { age }
+query q2 { name }`, rp)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := Execute(doc, "q", map[string]interface{}{"withMail": true}); err != nil ||
+		fmt.Sprint(res) != "map[user:map[age:42 email:alice@example.com n:Alice]]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := Execute(doc, "q", map[string]interface{}{"withMail": false}); err != nil ||
+		fmt.Sprint(res) != "map[user:map[age:42 n:Alice]]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := Execute(doc, "q2", nil); err != nil || fmt.Sprint(res) != "map[name:Alice]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := Execute(doc, "", nil); err == nil ||
+		err.Error() != "Operation name required for documents with multiple operations" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := Execute(doc, "q3", nil); err == nil || err.Error() != "Unknown operation: q3" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	rp := &testExecRuntimeProvider{map[string]interface{}{
+		"name": "Alice",
+		"age":  fmt.Errorf("Age is secret"),
+	}, []string{"foo", "bar"}}
+
+	doc, _ := ParseWithRuntime("mytest", `{ foo name bar }`, rp)
+
+	res, err := Execute(doc, "", nil)
+	if err == nil || err.Error() != "Invalid field foo; Invalid field bar" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if cerr, ok := err.(*errorutil.CompositeError); !ok || len(cerr.Errors) != 2 {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	doc, _ = ParseWithRuntime("mytest", `{ name age }`, rp)
+
+	if res, err := Execute(doc, "", nil); err == nil || err.Error() != "Age is secret" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	doc, _ = ParseWithRuntime("mytest", `{ ...a } fragment a on User { ...b } fragment b on User { ...a }`, rp)
+
+	if res, err := Execute(doc, "", nil); err == nil || err.Error() !=
+		"Parse error in AST: Cyclic fragment reference (a -> b -> a) (Line:1 Pos:62)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	doc, _ = Parse("mytest", `{ name }`)
+
+	if res, err := Execute(doc, "", nil); err == nil || err.Error() != "Field name has no runtime component" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestShapeResponse(t *testing.T) {
+
+	doc, err := Parse("mytest", `
+query q {
+  me: user(id: 1) {
+    name
+    bestFriend: friend {
+      name
+      age
+    }
+    ...extra
+  }
+  version
+  debug @skip(if: true)
+}
+fragment extra on User {
+  email
+  ... on User { friend { id } }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	op := doc.FindFirst(NodeOperationDefinition)
+
+	res, err := ShapeResponse(op, map[string]interface{}{
+		"me.name":            "Alice",
+		"me.bestFriend.name": "Bob",
+		"me.email":           "alice@example.com",
+		"me.friend.id":       2,
+		"version":            "1.0",
+		"debug":              true,
+		"user.name":          "unused",
+	})
+
+	if err != nil || fmt.Sprint(res) != "map[me:map[bestFriend:map[age:<nil> name:Bob] "+
+		"email:alice@example.com friend:map[id:2] name:Alice] version:1.0]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Fragments cannot be followed without a document
+
+	if res, err := ShapeResponse(op.Clone(), nil); err == nil ||
+		err.Error() != "Parse error in AST: Unknown fragment (extra) (Line:9 Pos:9)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	doc, _ = Parse("mytest", `{ ...a } fragment a on User { ...b } fragment b on User { ...a }`)
+
+	if res, err := ShapeResponse(doc.FindFirst(NodeOperationDefinition), nil); err == nil ||
+		err.Error() != "Parse error in AST: Cyclic fragment reference (a -> b -> a) (Line:1 Pos:62)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
@@ -10,10 +10,12 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
@@ -104,31 +106,205 @@ Function which represents the current state of the lexer and returns the next st
 */
 type lexFunc func() lexFunc
 
+/*
+LexOptions are options which change the behaviour of the lexer.
+*/
+type LexOptions struct {
+	RawBlockStrings bool // Return block strings without stripping indentation and blank lines
+	StrictBOM       bool // Only allow a byte order mark at the very beginning of the input
+	TabWidth        int  // Count token positions in display columns expanding tabs to this width (0 counts runes)
+	MaxStringLength int  // Maximum number of characters in a string value (0 for no limit)
+}
+
 /*
 Lexer data structure
 */
 type lexer struct {
-	name   string        // Name to identify the input
-	input  string        // Input string of the lexer
-	pos    int           // Current rune pointer
-	line   int           // Current line pointer
-	lastnl int           // Last newline position
-	width  int           // Width of last rune
-	start  int           // Start position of the current red token
-	tokens chan LexToken // Channel for lexer output
+	name     string        // Name to identify the input
+	input    string        // Input string of the lexer
+	pos      int           // Current rune pointer
+	line     int           // Current line pointer
+	lastnl   int           // Last newline position
+	width    int           // Width of last rune
+	start    int           // Start position of the current red token
+	tokens   chan LexToken // Channel for lexer output
+	options  LexOptions    // Lexer options
+	list     []LexToken    // List for lexer output (used if there is no channel)
+	interner *Interner     // Interner for name token values (can be nil)
 }
 
 /*
 Lex lexes a given input. Returns a channel which contains tokens.
 */
 func Lex(name string, input string) chan LexToken {
+	return LexWithOptions(name, input, LexOptions{})
+}
+
+/*
+LexWithOptions lexes a given input using the given lexer options. Returns a
+channel which contains tokens.
+*/
+func LexWithOptions(name string, input string, options LexOptions) chan LexToken {
+
+	l := &lexer{name, input, 0, 0, 0, 0, 0, make(chan LexToken), options, nil, nil}
+	go l.run()
+
+	return l.tokens
+}
+
+/*
+LexWithInterner lexes a given input and interns the values of all name tokens
+with the given interner. Returns a channel which contains tokens.
+*/
+func LexWithInterner(name string, input string, interner *Interner) chan LexToken {
+
+	l := &lexer{name, input, 0, 0, 0, 0, 0, make(chan LexToken), LexOptions{}, nil, interner}
+	go l.run()
+
+	return l.tokens
+}
 
-	l := &lexer{name, input, 0, 0, 0, 0, 0, make(chan LexToken)}
+/*
+LexFrom lexes a given input starting at a given byte offset. The line and
+column of the character at the offset must be given as they are reported by
+the Lline and Lpos fields of tokens (e.g. the position of a previously lexed
+token which starts at the offset). All produced tokens have the same
+positions as they would have when lexing the whole input. This can be used to
+re-lex only the changed region of an input. Returns a channel which contains
+tokens.
+*/
+func LexFrom(name string, input string, startPos, startLine, startCol int) chan LexToken {
+
+	if startPos < 0 {
+		startPos = 0
+	} else if startPos > len(input) {
+		startPos = len(input)
+	}
+
+	l := &lexer{name, input, startPos, startLine - 1, startPos - startCol + 1, 0, startPos,
+		make(chan LexToken), LexOptions{}, nil, nil}
 	go l.run()
 
 	return l.tokens
 }
 
+/*
+Interner maps identical strings to a single shared string. Interned strings
+do not reference the input they were taken from. An Interner can be reused
+for many inputs but must not be used concurrently.
+*/
+type Interner struct {
+	strings map[string]string
+}
+
+/*
+NewInterner creates a new Interner.
+*/
+func NewInterner() *Interner {
+	return &Interner{make(map[string]string)}
+}
+
+/*
+Intern returns the shared string which is identical to a given string.
+*/
+func (i *Interner) Intern(s string) string {
+
+	if is, ok := i.strings[s]; ok {
+		return is
+	}
+
+	// Copy the string so the interned string does not keep the input alive
+
+	is := string([]byte(s))
+	i.strings[is] = is
+
+	return is
+}
+
+/*
+Len returns the number of interned strings.
+*/
+func (i *Interner) Len() int {
+	return len(i.strings)
+}
+
+/*
+Lexer is a reusable lexer which produces a list of tokens. Unlike Lex it does
+not start a goroutine and reuses its token buffer. Lexer objects should be
+obtained from a pool with GetLexer and returned with PutLexer.
+*/
+type Lexer struct {
+	l lexer
+}
+
+/*
+lexerPool is a pool of reusable lexers.
+*/
+var lexerPool = sync.Pool{
+	New: func() interface{} {
+		return &Lexer{}
+	},
+}
+
+/*
+GetLexer returns a lexer from the pool.
+*/
+func GetLexer() *Lexer {
+	return lexerPool.Get().(*Lexer)
+}
+
+/*
+maxPooledTokens is the maximum capacity of a token list which is kept when a
+lexer is returned to the pool.
+*/
+const maxPooledTokens = 4096
+
+/*
+PutLexer returns a lexer to the pool. The lexer and any token list returned
+by it must not be used afterwards.
+*/
+func PutLexer(l *Lexer) {
+
+	// Clear all tokens so the pooled token list does not keep the previous
+	// input reachable - very large token lists are dropped
+
+	if cap(l.l.list) > maxPooledTokens {
+		l.l.list = nil
+	} else {
+		list := l.l.list[:cap(l.l.list)]
+
+		for i := range list {
+			list[i] = LexToken{}
+		}
+	}
+
+	l.Reset("", "")
+	lexerPool.Put(l)
+}
+
+/*
+Reset resets this lexer for a new input.
+*/
+func (l *Lexer) Reset(name string, input string) {
+	l.ResetWithOptions(name, input, LexOptions{})
+}
+
+/*
+ResetWithOptions resets this lexer for a new input using the given lexer options.
+*/
+func (l *Lexer) ResetWithOptions(name string, input string, options LexOptions) {
+	l.l = lexer{name, input, 0, 0, 0, 0, 0, nil, options, l.l.list[:0], nil}
+}
+
+/*
+Lex lexes the current input. Returns a list of tokens which is only valid
+until the lexer is reset.
+*/
+func (l *Lexer) Lex() []LexToken {
+	l.l.run()
+	return l.l.list
+}
+
 /*
 LexToList lexes a given input. Returns a list of tokens.
 */
@@ -142,6 +318,43 @@ func LexToList(name string, input string) []LexToken {
 	return tokens
 }
 
+/*
+CountTokens lexes a given input and returns the number of tokens (excluding
+the final EOF token) without building an AST. Returns the number of tokens
+which were lexed before the first lexical error together with the error.
+*/
+func CountTokens(name string, input string) (int, error) {
+	var count int
+	var err error
+
+	for t := range Lex(name, input) {
+
+		if t.ID == TokenError && err == nil {
+			err = lexerError(name, t)
+		} else if t.ID != TokenEOF && err == nil {
+			count++
+		}
+	}
+
+	return count, err
+}
+
+/*
+DumpTokens lexes a given input and returns a human-readable listing of all
+tokens. Each line contains the number of the token, the name of its ID, its
+quoted value and its position. This is useful for debugging lexer issues.
+*/
+func DumpTokens(name string, input string) string {
+	var buf bytes.Buffer
+
+	for i, t := range LexToList(name, input) {
+		buf.WriteString(fmt.Sprintf("%3d: %-16s %-12s %s\n", i+1, t.ID,
+			strconv.Quote(t.Val), t.PosString()))
+	}
+
+	return buf.String()
+}
+
 /*
 run is the main loop of the lexer.
 */
@@ -157,7 +370,9 @@ func (l *lexer) run() {
 		}
 	}
 
-	close(l.tokens)
+	if l.tokens != nil {
+		close(l.tokens)
+	}
 }
 
 /*
@@ -219,14 +434,106 @@ func (l *lexer) startNew() {
 emitTokenAndValue passes a token with a given value back to the client.
 */
 func (l *lexer) emitToken(i LexTokenID, val string) {
+	if l.interner != nil && i == TokenName {
+		val = l.interner.Intern(val)
+	}
+
+	t := LexToken{i, l.start, val, l.line + 1, l.start - l.lastnl + 1}
+
+	if l.options.TabWidth > 0 {
+		t.Lpos = l.column()
+	}
+
 	if l.tokens != nil {
-		l.tokens <- LexToken{i, l.start, val, l.line + 1, l.start - l.lastnl + 1}
+		l.tokens <- t
+	} else {
+		l.list = append(l.list, t)
 	}
 }
 
+/*
+column returns the position of the current token in its line counted in
+display columns. Tabs advance the column to the next tab stop.
+*/
+func (l *lexer) column() int {
+	var col int
+
+	lineStart, offset := l.lastnl, 1
+	if l.line > 0 {
+		lineStart++ // Skip the newline character itself
+		offset++
+	}
+
+	for _, r := range l.input[lineStart:l.start] {
+		if r == '\t' {
+			col += l.options.TabWidth - col%l.options.TabWidth
+		} else {
+			col++
+		}
+	}
+
+	return col + offset
+}
+
 // State functions
 // ===============
 
+/*
+IsValidName checks if a given string is a valid GraphQL name. (@spec 2.1.9)
+*/
+func IsValidName(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			(i > 0 && c >= '0' && c <= '9')) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+IsValidEnumValue checks if a given string is a valid GraphQL enum value. Enum
+values are names which are not true, false or null. (@spec 2.9.6)
+*/
+func IsValidEnumValue(s string) bool {
+	return IsValidName(s) && s != "true" && s != "false" && s != "null"
+}
+
+/*
+IsValidUTF8 checks if a given input is valid UTF-8. Returns the byte offset
+of the first invalid sequence or -1 if the input is valid. The lexer replaces
+invalid sequences with replacement runes so this function can be used to
+report a precise error before lexing.
+*/
+func IsValidUTF8(s string) (bool, int) {
+	for i := 0; i < len(s); {
+		r, w := utf8.DecodeRuneInString(s[i:])
+
+		if r == utf8.RuneError && w == 1 {
+			return false, i
+		}
+
+		i += w
+	}
+
+	return true, -1
+}
+
+// Patterns to classify tokens - compiled once since they are used for every token
+
+var zeroPattern = regexp.MustCompile("^-?0$")
+var intPattern = regexp.MustCompile("^-?[1-9][0-9]*$")
+var float1Pattern = regexp.MustCompile("^[0-9]*\\.[0-9]*$")
+var float2Pattern = regexp.MustCompile("^[0-9][eE][+-]?[0-9]*$")
+var float3Pattern = regexp.MustCompile("^[0-9]*\\.[0-9][eE][+-]?[0-9]*$")
+
 /*
 lexToken is the main entry function for the lexer.
 */
@@ -237,6 +544,13 @@ func (l *lexer) lexToken() lexFunc {
 
 	token := l.input[l.start:l.pos]
 
+	// Check for a byte order mark which is not at the beginning of the input
+
+	if l.options.StrictBOM && strings.ContainsRune(token, '\ufeff') {
+		l.emitToken(TokenError, ErrUnexpectedBOM.Error())
+		return nil
+	}
+
 	// Check for Comment - @spec 2.1.4, 2.1.7
 
 	if token == "#" {
@@ -260,28 +574,22 @@ func (l *lexer) lexToken() lexFunc {
 
 	// Check for Name - @spec 2.1.9
 
-	isName, _ := regexp.MatchString("^[_A-Za-z][_0-9A-Za-z]*$", token)
-	if isName {
+	if IsValidName(token) {
 		l.emitToken(TokenName, token)
 		return l.lexToken
 	}
 
 	// Check for IntValue - @spec 2.9.1
 
-	isZero, _ := regexp.MatchString("^-?0$", token)
-	isInt, _ := regexp.MatchString("^-?[1-9][0-9]*$", token)
-	if isZero || isInt {
+	if zeroPattern.MatchString(token) || intPattern.MatchString(token) {
 		l.emitToken(TokenIntValue, token)
 		return l.lexToken
 	}
 
 	// Check for FloatValue - @spec 2.9.2
 
-	isFloat1, _ := regexp.MatchString("^[0-9]*\\.[0-9]*$", token)
-	isFloat2, _ := regexp.MatchString("^[0-9][eE][+-]?[0-9]*$", token)
-	isFloat3, _ := regexp.MatchString("^[0-9]*\\.[0-9][eE][+-]?[0-9]*$", token)
-
-	if isFloat1 || isFloat2 || isFloat3 {
+	if float1Pattern.MatchString(token) || float2Pattern.MatchString(token) ||
+		float3Pattern.MatchString(token) {
 		l.emitToken(TokenFloatValue, strings.ToLower(token))
 		return l.lexToken
 	}
@@ -335,7 +643,8 @@ Values can be declared in different ways:
 
 " ... " A normal string (escape sequences are interpreted)
 
-""" ... """ A multi-line string (escape sequences are not interpreted)
+""" ... """ A multi-line string (escape sequences are not interpreted except
+for \""" which represents """)
 */
 func (l *lexer) lexStringValue() lexFunc {
 	var isEnd func(rune) bool
@@ -372,9 +681,25 @@ func (l *lexer) lexStringValue() lexFunc {
 	r := l.next(-1)
 	lLine := l.line
 	lLastnl := l.lastnl
+	length := 0
+
+	// Check the length of the string after each consumed character (the error
+	// points to the start of the string)
+
+	tooLong := func(n int) bool {
+		if length += n; l.options.MaxStringLength > 0 && length > l.options.MaxStringLength {
+			l.emitToken(TokenError, ErrStringTooLong.Error())
+			return true
+		}
+		return false
+	}
 
 	for !isEnd(r) {
 
+		if tooLong(1) {
+			return nil
+		}
+
 		if r == '\n' {
 			lLine++
 			lLastnl = l.pos
@@ -385,12 +710,28 @@ func (l *lexer) lexStringValue() lexFunc {
 		if r == RuneEOF {
 			l.emitToken(TokenError, "EOF inside quotes")
 			return nil
-		} else if r == '\\' {
+		}
+
+		// Consume escaped characters (an escape sequence can directly follow
+		// another one) - block strings only escape triple quotes
+
+		for r == '\\' {
 
-			// Consume escaped characters
+			if isBlockString && l.next(0) == '"' && l.next(1) == '"' && l.next(2) == '"' {
+				l.next(-1)
+				l.next(-1)
+
+				if tooLong(2) {
+					return nil
+				}
+			}
 
 			r = l.next(-1)
 			r = l.next(-1)
+
+			if tooLong(2) {
+				return nil
+			}
 		}
 	}
 
@@ -412,15 +753,17 @@ func (l *lexer) lexStringValue() lexFunc {
 		l.next(-1)
 		l.next(-1)
 
-		token := l.input[l.start+3 : l.pos-3]
+		token := strings.Replace(l.input[l.start+3:l.pos-3], `\"""`, `"""`, -1)
 
 		// Since block strings represent freeform text often used in indented
 		// positions, the string value semantics of a block string excludes uniform
 		// indentation and blank initial and trailing lines
 		// (from spec about 'Block Strings')
 
-		token = stringutil.StripUniformIndentation(token)
-		token = stringutil.TrimBlankLines(token)
+		if !l.options.RawBlockStrings {
+			token = stringutil.StripUniformIndentation(token)
+			token = stringutil.TrimBlankLines(token)
+		}
 
 		l.emitToken(TokenStringValue, token)
 	}
@@ -441,7 +784,7 @@ func (l *lexer) isIgnoredRune(r rune) bool {
 	// Ignored tokens - @spec 2.1.1, 2.1.2, 2.1.3, 2.1.3, 2.1.5, 2.1.7
 
 	return unicode.IsSpace(r) || unicode.IsControl(r) || r == RuneEOF ||
-		r == RuneComma || r == '\ufeff'
+		r == RuneComma || (r == '\ufeff' && (!l.options.StrictBOM || l.pos == 0))
 }
 
 /*
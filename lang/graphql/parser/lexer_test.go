@@ -11,11 +11,15 @@ package parser
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"unsafe"
 )
 
 func TestNextAndPeek(t *testing.T) {
-	l := &lexer{"", "Test", 0, 0, 0, 0, 0, make(chan LexToken)}
+	l := &lexer{"", "Test", 0, 0, 0, 0, 0, make(chan LexToken), LexOptions{}, nil, nil}
 
 	if res := fmt.Sprintf("%c", l.next(0)); res != "T" {
 		t.Error("Unexpected result:", res)
@@ -91,6 +95,94 @@ func TestLexingErrors(t *testing.T) {
 	}
 }
 
+func TestCountTokens(t *testing.T) {
+
+	for _, input := range []string{
+		"",
+		"{ user }",
+		`query q($id: ID = 1) { user(id: $id, name: "foo") @include(if: true) { ...f } } # comment`,
+		"\ufeff{\n\ta(b: \"\"\"x\ny\"\"\", c: [1.5, -2]) {\n\t\tfoo\n\t}\n}",
+	} {
+		if res, err := CountTokens("test", input); err != nil || res != len(LexToList("test", input))-1 {
+			t.Error("Unexpected result:", input, res, err)
+			return
+		}
+	}
+
+	if res, err := CountTokens("test", `{ user(name: "foo) }`); res != 5 || err == nil ||
+		err.Error() != "Parse error in test: Lexical error (EOF inside quotes) (Line:1 Pos:14)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestLexTokenIDNames(t *testing.T) {
+	ids := []LexTokenID{TokenError, TokenEOF, TokenPunctuator, TokenName,
+		TokenIntValue, TokenFloatValue, TokenStringValue, TokenGeneral}
+
+	if res := fmt.Sprint(ids); res != "[TokenError TokenEOF TokenPunctuator TokenName "+
+		"TokenIntValue TokenFloatValue TokenStringValue TokenGeneral]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	for _, id := range ids {
+		if res, ok := TokenIDByName(id.String()); !ok || res != id {
+			t.Error("Unexpected result:", res, ok)
+			return
+		}
+	}
+
+	if res := LexTokenID(42).String(); res != "LexTokenID(42)" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, ok := TokenIDByName("TokenFoo"); ok || res != 0 {
+		t.Error("Unexpected result:", res, ok)
+		return
+	}
+}
+
+func TestDumpTokens(t *testing.T) {
+
+	if res := DumpTokens("test", `{
+  user(id: 1.5, name: "a\"b") @x
+}`); res != `
+  1: TokenPunctuator  "{"          Line 1, Pos 1
+  2: TokenName        "user"       Line 2, Pos 4
+  3: TokenPunctuator  "("          Line 2, Pos 8
+  4: TokenName        "id"         Line 2, Pos 9
+  5: TokenPunctuator  ":"          Line 2, Pos 11
+  6: TokenFloatValue  "1.5"        Line 2, Pos 13
+  7: TokenName        "name"       Line 2, Pos 18
+  8: TokenPunctuator  ":"          Line 2, Pos 22
+  9: TokenStringValue "a\"b"       Line 2, Pos 24
+ 10: TokenPunctuator  ")"          Line 2, Pos 30
+ 11: TokenPunctuator  "@"          Line 2, Pos 32
+ 12: TokenName        "x"          Line 2, Pos 33
+ 13: TokenPunctuator  "}"          Line 3, Pos 2
+ 14: TokenEOF         ""           Line 3, Pos 2
+`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := DumpTokens("test", `"te`); res != `
+  1: TokenError       "EOF inside quotes" Line 1, Pos 1
+  2: TokenEOF         ""           Line 1, Pos 3
+`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := DumpTokens("test", ""); res != `  1: TokenEOF         ""           Line 1, Pos 0
+` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func TestMultilineLexing(t *testing.T) {
 
 	if res := fmt.Sprint(LexToList("test", `1!23#...4e+11
@@ -151,6 +243,48 @@ Yours,
 	}
 }
 
+func TestRawBlockStringLexing(t *testing.T) {
+
+	input := `"""
+    Hello,
+      World!
+  """`
+
+	var tokens []LexToken
+
+	for t := range LexWithOptions("test", input, LexOptions{RawBlockStrings: true}) {
+		tokens = append(tokens, t)
+	}
+
+	if res := fmt.Sprint(tokens); res != `["
+    Hello,
+      World!
+  " EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprint(LexToList("test", input)); res != `["Hello,
+  World!" EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	ast, err := ParseWithOptions("test", `{ foo(bar: """
+  raw
+""") }`, nil, LexOptions{RawBlockStrings: true})
+
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if val := ast.Children[0].Children[0].Children[0].Children[0].Children[1].Children[0].Children[1].Token.Val; val != "\n  raw\n" {
+		t.Error("Unexpected result:", val)
+		return
+	}
+}
+
 func TestIgnoredLexing(t *testing.T) {
 
 	res := fmt.Sprint(LexToList("test", "1,2,3...abc\t\r\n#123\n"))
@@ -175,6 +309,153 @@ func TestIgnoredLexing(t *testing.T) {
 	}
 }
 
+func TestBOMLexing(t *testing.T) {
+	strict := func(input string) string {
+		var tokens []LexToken
+
+		for t := range LexWithOptions("test", input, LexOptions{StrictBOM: true}) {
+			tokens = append(tokens, t)
+		}
+
+		return fmt.Sprint(tokens)
+	}
+
+	if res := fmt.Sprint(LexToList("test", "\ufeff{ a\ufeff b }")); res != `[{ <a> <b> } EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := strict("\ufeff{ a b }"); res != `[{ <a> <b> } EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := strict("{ a \ufeff b }"); res != `[{ <a> Error: Unexpected byte order mark (Line 1, Pos 5)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := strict("{ a\ufeff b }"); res != `[{ Error: Unexpected byte order mark (Line 1, Pos 3)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, err := ParseWithOptions("test", "{ a \ufeff b }", nil, LexOptions{StrictBOM: true}); err == nil ||
+		err.Error() != "Parse error in test: Unexpected byte order mark (Line:1 Pos:5)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestTabWidthLexing(t *testing.T) {
+	input := "{\n\tuser {\n\t\tid  \tname\n\t}\n}"
+
+	positions := func(options LexOptions) string {
+		var res []string
+
+		for t := range LexWithOptions("test", input, options) {
+			res = append(res, fmt.Sprintf("%v:%v:%v", t.Val, t.Lline, t.Lpos))
+		}
+
+		return fmt.Sprint(res)
+	}
+
+	if res := positions(LexOptions{}); res != "[{:1:1 user:2:3 {:2:8 id:3:4 name:3:9 }:4:3 }:5:2 :5:2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := positions(LexOptions{TabWidth: 4}); res != "[{:1:1 user:2:6 {:2:11 id:3:10 name:3:18 }:4:6 }:5:2 :5:2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := positions(LexOptions{TabWidth: 8}); res != "[{:1:1 user:2:10 {:2:15 id:3:18 name:3:26 }:4:10 }:5:2 :5:2]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, err := ParseWithOptions("test", "{\n\tuser(id: ) }", nil, LexOptions{TabWidth: 4}); err == nil ||
+		err.Error() != "Parse error in test: Term cannot start an expression (}) (Line:2 Pos:17)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestMaxStringLengthLexing(t *testing.T) {
+
+	lex := func(input string) string {
+		var tokens []LexToken
+
+		for t := range LexWithOptions("test", input, LexOptions{MaxStringLength: 5}) {
+			tokens = append(tokens, t)
+		}
+
+		return fmt.Sprint(tokens)
+	}
+
+	if res := lex(`{ a(b: "12345", c: """abcde""", d: "a\"cd") }`); res !=
+		`[{ <a> ( <b> : "12345" <c> : "abcde" <d> : "a"cd" ) } EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex(`{ a(b: "123456") }`); res != `[{ <a> ( <b> : Error: String value too long (Line 1, Pos 8)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex("{\n  a(b: \"\"\"abc\n def\"\"\") }"); res != `[{ <a> ( <b> : Error: String value too long (Line 2, Pos 9)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex(`{ a(b: "a\"cde") }`); res != `[{ <a> ( <b> : Error: String value too long (Line 1, Pos 8)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Escape sequences count towards the limit
+
+	if res := lex(`{ a(b: "ab\"") }`); res != `[{ <a> ( <b> : "ab"" ) } EOF]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex(`{ a(b: "abcd\"") }`); res != `[{ <a> ( <b> : Error: String value too long (Line 1, Pos 8)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex(`{ a(b: "` + strings.Repeat(`\n`, 1000) + `") }`); res !=
+		`[{ <a> ( <b> : Error: String value too long (Line 1, Pos 8)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lex(`{ a(b: """ab\"""""") }`); res != `[{ <a> ( <b> : Error: String value too long (Line 1, Pos 8)]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if _, err := ParseWithOptions("test", `{ a(b: "ab\"") }`, nil, LexOptions{MaxStringLength: 3}); err == nil ||
+		err.Error() != "Parse error in test: String value too long (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ParseWithOptions("test", `{ a(b: "123456") }`, nil, LexOptions{MaxStringLength: 5}); err == nil ||
+		err.Error() != "Parse error in test: String value too long (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ParseWithOptions("test", `{ a(b: "123456") }`, nil, LexOptions{}); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
 func TestSampleQueries(t *testing.T) {
 
 	sampleQueries := [][]string{{`
@@ -244,3 +525,315 @@ query StudentsJPA {
 		}
 	}
 }
+
+func TestPooledLexer(t *testing.T) {
+	inputs := []string{
+		"{\n  foo(a: 1)\n  bar\n}",
+		`"""
+  block
+"""`,
+		"1,2,3 .. x abc\r\n#123\n",
+		"query q($a: Int=1) {\n\n  user(id: $a) { ...f }\n}",
+		`"unterminated`,
+	}
+
+	// Check that the pooled lexer produces the same tokens as the channel lexer
+	// and that no state leaks between uses
+
+	l := GetLexer()
+
+	for i := 0; i < 3; i++ {
+		for _, input := range inputs {
+			l.Reset("test", input)
+
+			if res, expected := fmt.Sprintf("%#v", l.Lex()), fmt.Sprintf("%#v", LexToList("test", input)); res != expected {
+				t.Error("Unexpected result:", res, "expected:", expected)
+				return
+			}
+		}
+	}
+
+	l.ResetWithOptions("test", `"""
+  raw
+"""`, LexOptions{RawBlockStrings: true})
+
+	if res := fmt.Sprint(l.Lex()); res != "[\"\n  raw\n\" EOF]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	l.Reset("test", `"""
+  raw
+"""`)
+
+	if res := fmt.Sprint(l.Lex()); res != "[\"raw\" EOF]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	PutLexer(l)
+
+	// The pooled token list does not reference the previous input
+
+	list := l.l.list[:cap(l.l.list)]
+
+	if len(list) == 0 {
+		t.Error("Token list should be kept")
+		return
+	}
+
+	for _, token := range list {
+		if token != (LexToken{}) {
+			t.Error("Unexpected token:", token)
+			return
+		}
+	}
+
+	// Very large token lists are not kept
+
+	l = GetLexer()
+	l.Reset("test", strings.Repeat("a ", maxPooledTokens))
+	l.Lex()
+	PutLexer(l)
+
+	if l.l.list != nil {
+		t.Error("Token list should have been dropped:", cap(l.l.list))
+		return
+	}
+
+	// Lex and parse concurrently with pooled lexers
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			input := fmt.Sprintf("{\n  field%v(arg: %v) {\n    sub%v\n  }\n}", i, i, i)
+
+			for j := 0; j < 20; j++ {
+				l := GetLexer()
+				l.Reset("test", input)
+				res := fmt.Sprint(l.Lex())
+				PutLexer(l)
+
+				if expected := fmt.Sprint(LexToList("test", input)); res != expected {
+					errs <- fmt.Errorf("Unexpected result: %v expected: %v", res, expected)
+					return
+				}
+
+				ast, err := Parse("test", input)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if pp, _ := PrettyPrint(ast); pp != input {
+					errs <- fmt.Errorf("Unexpected result: %v expected: %v", pp, input)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+		return
+	}
+}
+
+func BenchmarkLexing(b *testing.B) {
+	input := benchmarkInput()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		LexToList("bench", input)
+	}
+}
+
+func BenchmarkPooledLexing(b *testing.B) {
+	input := benchmarkInput()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		l := GetLexer()
+		l.Reset("bench", input)
+		l.Lex()
+		PutLexer(l)
+	}
+}
+
+func BenchmarkInternedLexing(b *testing.B) {
+	input := benchmarkInput()
+	interner := NewInterner()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for range LexWithInterner("bench", input, interner) {
+		}
+	}
+}
+
+func benchmarkInput() string {
+	var buf strings.Builder
+
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&buf, "field%v(arg: %v, flt: %v.5e3, name: \"test\") { sub @include(if: true) }\n", i, i, i)
+	}
+
+	return "{\n" + buf.String() + "}"
+}
+
+func TestInternedLexing(t *testing.T) {
+	var names []string
+
+	stringData := func(s string) uintptr {
+		return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+	}
+
+	interner := NewInterner()
+
+	for _, input := range []string{"{ user { name } }", "{ user { id name } }"} {
+		var tokens []LexToken
+
+		for t := range LexWithInterner("test", input, interner) {
+			tokens = append(tokens, t)
+
+			if t.ID == TokenName {
+				names = append(names, t.Val)
+			}
+		}
+
+		if res := fmt.Sprint(tokens); res != fmt.Sprint(LexToList("test", input)) {
+			t.Error("Unexpected result:", res)
+			return
+		}
+	}
+
+	if fmt.Sprint(names) != "[user name user id name]" || interner.Len() != 3 {
+		t.Error("Unexpected result:", names, interner.Len())
+		return
+	}
+
+	// Identical names share the same string data
+
+	if stringData(names[0]) != stringData(names[2]) || stringData(names[1]) != stringData(names[4]) {
+		t.Error("Interned strings should share the same data")
+		return
+	}
+
+	// Tokens without interning point into the input
+
+	tokens := LexToList("test", "{ user user }")
+
+	if stringData(tokens[1].Val) == stringData(tokens[2].Val) {
+		t.Error("Strings should not share the same data")
+		return
+	}
+}
+
+func TestIsValidName(t *testing.T) {
+	testdata := []string{"foo", "_foo", "Foo_1", "_", "", "1foo", "foo-bar", "föo", "true", "null"}
+	expected := []bool{true, true, true, true, false, false, false, false, true, true}
+	expectedEnum := []bool{true, true, true, true, false, false, false, false, false, false}
+
+	for i, s := range testdata {
+		if res := IsValidName(s); res != expected[i] {
+			t.Error("Unexpected result for", s, ":", res)
+		}
+		if res := IsValidEnumValue(s); res != expectedEnum[i] {
+			t.Error("Unexpected enum result for", s, ":", res)
+		}
+	}
+}
+
+func TestIsValidUTF8(t *testing.T) {
+
+	for _, s := range []string{"", "{ user }", "\ufeff{ a(s: \"äöü €😀\") }", "\ufffd"} {
+		if ok, pos := IsValidUTF8(s); !ok || pos != -1 {
+			t.Error("Unexpected result:", s, ok, pos)
+			return
+		}
+	}
+
+	testdata := []string{"{ a\xff }", "\xc3", "ab\xc3\x28", "€\xe2\x82", "\xed\xa0\x80", "\xc0\xaf"}
+	expected := []int{3, 0, 2, 3, 0, 0}
+
+	for i, s := range testdata {
+		if ok, pos := IsValidUTF8(s); ok || pos != expected[i] {
+			t.Error("Unexpected result:", fmt.Sprintf("%q", s), ok, pos)
+			return
+		}
+	}
+}
+
+func TestLexFrom(t *testing.T) {
+	input := `query q {
+  user(id: 1) {
+    name   # comment
+    friends(first: 10, after: "x") { id }
+  }
+}`
+	lexFrom := func(startPos, startLine, startCol int) []LexToken {
+		var tokens []LexToken
+
+		for t := range LexFrom("test", input, startPos, startLine, startCol) {
+			tokens = append(tokens, t)
+		}
+
+		return tokens
+	}
+
+	baseline := LexToList("test", input)
+
+	// Lexing from the start of any token produces the same tokens as the full lex
+
+	for i, token := range baseline[:len(baseline)-1] {
+		if res := lexFrom(token.Pos, token.Lline, token.Lpos); !reflect.DeepEqual(res, baseline[i:]) {
+			t.Error("Unexpected result:", i, res, baseline[i:])
+			return
+		}
+	}
+
+	// Start in the middle of a line before some whitespace (after "name")
+
+	start := strings.Index(input, "name") + 4
+	res := lexFrom(start, 3, 10)
+
+	if fmt.Sprint(res[0], res[0].Lline, res[0].Lpos) != "<friends> 4 6" ||
+		!reflect.DeepEqual(res, baseline[len(baseline)-len(res):]) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Start before some whitespace on the same line (after "id:")
+
+	start = strings.Index(input, "id:") + 3
+	res = lexFrom(start, 2, 12)
+
+	if fmt.Sprint(res[0], res[0].Lline, res[0].Lpos) != "int(1) 2 13" ||
+		!reflect.DeepEqual(res, baseline[len(baseline)-len(res):]) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lexFrom(len(input)+10, 6, 3); fmt.Sprint(res) != "[EOF]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := lexFrom(-1, 1, 1); !reflect.DeepEqual(res, baseline) {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
@@ -11,7 +11,9 @@ package parser
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/krotik/common/stringutil"
 )
@@ -24,6 +26,7 @@ type ASTNode struct {
 	Token    *LexToken  // Lexer token of this ASTNode
 	Children []*ASTNode // Child nodes
 	Runtime  Runtime    // Runtime component for this ASTNode
+	Parent   *ASTNode   `json:"-"` // Parent node (nil for the root node)
 
 	binding        int                                                             // Binding power of this node
 	nullDenotation func(p *parser, self *ASTNode) (*ASTNode, error)                // Configure token as beginning node
@@ -83,15 +86,34 @@ func ASTFromPlain(plainAST map[string]interface{}) (*ASTNode, error) {
 		}
 	}
 
-	return &ASTNode{fmt.Sprint(name), &LexToken{TokenGeneral, 0,
-		fmt.Sprint(value), 0, 0}, astChildren, nil, 0, nil, nil}, nil
+	ret := &ASTNode{fmt.Sprint(name), &LexToken{TokenGeneral, 0,
+		fmt.Sprint(value), 0, 0}, astChildren, nil, nil, 0, nil, nil}
+
+	for _, child := range astChildren {
+		child.Parent = ret
+	}
+
+	return ret, nil
+}
+
+/*
+ASTFromJSON creates an AST from a plain AST which is encoded as JSON.
+*/
+func ASTFromJSON(data []byte) (*ASTNode, error) {
+	var plainAST map[string]interface{}
+
+	if err := json.Unmarshal(data, &plainAST); err != nil {
+		return nil, fmt.Errorf("Could not decode plain ast: %v", err)
+	}
+
+	return ASTFromPlain(plainAST)
 }
 
 /*
 newAstNode creates an instance of this ASTNode which is connected to a concrete lexer token.
 */
 func newAstNode(name string, p *parser, t *LexToken) *ASTNode {
-	ret := &ASTNode{name, t, make([]*ASTNode, 0, 2), nil, 0, nil, nil}
+	ret := &ASTNode{name, t, make([]*ASTNode, 0, 2), nil, nil, 0, nil, nil}
 	if p.rp != nil {
 		ret.Runtime = p.rp.Runtime(ret)
 	}
@@ -114,13 +136,36 @@ func changeAstNode(node *ASTNode, newname string, p *parser) *ASTNode {
 instane creates a new instance of this ASTNode which is connected to a concrete lexer token.
 */
 func (n *ASTNode) instance(p *parser, t *LexToken) *ASTNode {
-	ret := &ASTNode{n.Name, t, make([]*ASTNode, 0, 2), nil, n.binding, n.nullDenotation, n.leftDenotation}
+	ret := &ASTNode{n.Name, t, make([]*ASTNode, 0, 2), nil, nil, n.binding, n.nullDenotation, n.leftDenotation}
 	if p.rp != nil {
 		ret.Runtime = p.rp.Runtime(ret)
 	}
 	return ret
 }
 
+/*
+Clone creates a deep copy of this ASTNode and all its children. The copy does
+not contain runtime components and has no parent.
+*/
+func (n *ASTNode) Clone() *ASTNode {
+	var token *LexToken
+
+	if n.Token != nil {
+		t := *n.Token
+		token = &t
+	}
+
+	ret := &ASTNode{n.Name, token, make([]*ASTNode, len(n.Children)), nil, nil,
+		n.binding, n.nullDenotation, n.leftDenotation}
+
+	for i, child := range n.Children {
+		ret.Children[i] = child.Clone()
+		ret.Children[i].Parent = ret
+	}
+
+	return ret
+}
+
 /*
 Plain returns this ASTNode and all its children as plain AST. A plain AST
 only contains map objects, lists and primitive types which can be serialized
@@ -181,3 +226,256 @@ func (n *ASTNode) levelString(indent int, buf *bytes.Buffer) {
 		child.levelString(indent+1, buf)
 	}
 }
+
+/*
+Path returns a slash-delimited path of node names from the root of the AST to
+this node (e.g. Document/ExecutableDefinition/OperationDefinition/SelectionSet/Field[1]).
+The position among siblings with the same name is added in brackets if there
+is more than one such sibling. The path is only available for ASTs which were
+produced by the parser or which had their parents set with SetParents.
+*/
+func (n *ASTNode) Path() string {
+	var path []string
+
+	for node := n; node != nil; node = node.Parent {
+		segment := node.Name
+
+		if node.Parent != nil {
+			var index, count int
+
+			for _, sibling := range node.Parent.Children {
+				if sibling == node {
+					index = count
+				}
+				if sibling.Name == node.Name {
+					count++
+				}
+			}
+
+			if count > 1 {
+				segment = fmt.Sprintf("%v[%v]", segment, index)
+			}
+		}
+
+		path = append([]string{segment}, path...)
+	}
+
+	return strings.Join(path, "/")
+}
+
+/*
+SetParents sets the parent of all nodes of a given AST. This is necessary for
+ASTs which were built or modified outside of this package.
+*/
+func SetParents(n *ASTNode) {
+
+	for _, child := range n.Children {
+		child.Parent = n
+		SetParents(child)
+	}
+}
+
+/*
+FindField returns the field with a given response key (alias or name if there
+is no alias) from a given selection set. Nested selection sets are not
+searched. Returns nil if there is no such field.
+*/
+func FindField(selectionSet *ASTNode, responseKey string) *ASTNode {
+
+	for _, child := range selectionSet.Children {
+		if child.Name == NodeField && fieldResponseKey(child) == responseKey {
+			return child
+		}
+	}
+
+	return nil
+}
+
+/*
+HasField checks if a given selection set contains a field with a given
+response key.
+*/
+func HasField(selectionSet *ASTNode, responseKey string) bool {
+	return FindField(selectionSet, responseKey) != nil
+}
+
+/*
+ObjectField returns the value of the field with a given name from a given
+object value node. The fields of an object value are kept in source order -
+if a name is given more than once the first field is returned.
+*/
+func ObjectField(objectValue *ASTNode, name string) (*ASTNode, bool) {
+
+	if objectValue.Name != NodeObjectValue {
+		return nil, false
+	}
+
+	for _, child := range objectValue.Children {
+		if child.Name == NodeObjectField && child.Token.Val == name && len(child.Children) > 0 {
+			return child.Children[0], true
+		}
+	}
+
+	return nil, false
+}
+
+/*
+fieldResponseKey returns the response key of a field node.
+*/
+func fieldResponseKey(field *ASTNode) string {
+
+	for _, child := range field.Children {
+		if child.Name == NodeAlias || child.Name == NodeName {
+			return child.Token.Val
+		}
+	}
+
+	return ""
+}
+
+/*
+FindAll returns all descendant nodes of this ASTNode which have a given name
+(e.g. all Field nodes). The nodes are returned in depth-first order.
+*/
+func (n *ASTNode) FindAll(name string) []*ASTNode {
+	var ret []*ASTNode
+
+	for _, child := range n.Children {
+		if child.Name == name {
+			ret = append(ret, child)
+		}
+
+		ret = append(ret, child.FindAll(name)...)
+	}
+
+	return ret
+}
+
+/*
+FindFirst returns the first descendant node of this ASTNode (in depth-first
+order) which has a given name. Returns nil if there is no such node.
+*/
+func (n *ASTNode) FindFirst(name string) *ASTNode {
+
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+
+		if res := child.FindFirst(name); res != nil {
+			return res
+		}
+	}
+
+	return nil
+}
+
+/*
+Directive returns the directive with the given name from this ASTNode. The
+node must have a Directives child (e.g. a Field or OperationDefinition).
+*/
+func (n *ASTNode) Directive(name string) (*ASTNode, bool) {
+
+	for _, child := range n.Children {
+		if child.Name != NodeDirectives {
+			continue
+		}
+
+		for _, directive := range child.Children {
+			if directive.Name == NodeDirective && len(directive.Children) > 0 &&
+				directive.Children[0].Name == NodeName && directive.Children[0].Token.Val == name {
+				return directive, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+/*
+DirectiveArg returns the value of an argument of a given directive. Simple
+values (Value, EnumValue and Variable nodes) are returned as their string
+value; list and object values are returned as *ASTNode.
+*/
+func (n *ASTNode) DirectiveArg(directiveName, argName string) (interface{}, bool) {
+
+	directive, ok := n.Directive(directiveName)
+	if !ok {
+		return nil, false
+	}
+
+	for _, child := range directive.Children {
+		if child.Name != NodeArguments {
+			continue
+		}
+
+		for _, arg := range child.Children {
+			if arg.Name == NodeArgument && len(arg.Children) > 1 &&
+				arg.Children[0].Name == NodeName && arg.Children[0].Token.Val == argName {
+
+				value := arg.Children[1]
+				if value.Name == NodeListValue || value.Name == NodeObjectValue {
+					return value, true
+				}
+
+				return value.Token.Val, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+/*
+PlainGet navigates a plain AST and returns the value at the given path. A path
+element can be a string which is used as a map key or an int which is used as
+an index into the children list of the current node. For example the path
+(0, 1, "name") returns the name of the second child of the first child.
+*/
+func PlainGet(plain map[string]interface{}, path ...interface{}) (interface{}, bool) {
+	var current interface{} = plain
+
+	for _, p := range path {
+
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		switch key := p.(type) {
+
+		case string:
+			if current, ok = node[key]; !ok {
+				return nil, false
+			}
+
+		case int:
+			children, ok := node["children"]
+			if !ok {
+				return nil, false
+			}
+
+			switch c := children.(type) {
+			case []map[string]interface{}:
+				if key < 0 || key >= len(c) {
+					return nil, false
+				}
+				current = c[key]
+
+			case []interface{}:
+				if key < 0 || key >= len(c) {
+					return nil, false
+				}
+				current = c[key]
+
+			default:
+				return nil, false
+			}
+
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
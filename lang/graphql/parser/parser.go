@@ -27,31 +27,31 @@ var astNodeMapIgnoredValues map[string]*ASTNode
 
 func init() {
 	astNodeMapValues = map[string]*ASTNode{
-		"query":        {NodeOperationDefinition, nil, nil, nil, 0, ndOperationDefinition, nil},
-		"mutation":     {NodeOperationDefinition, nil, nil, nil, 0, ndOperationDefinition, nil},
-		"subscription": {NodeOperationDefinition, nil, nil, nil, 0, ndOperationDefinition, nil},
-		"fragment":     {NodeFragmentDefinition, nil, nil, nil, 0, ndFragmentDefinition, nil},
-		"{":            {NodeSelectionSet, nil, nil, nil, 0, ndSelectionSet, nil},
-		"(":            {NodeArguments, nil, nil, nil, 0, ndArgsOrVarDef, nil},
-		"@":            {NodeDirectives, nil, nil, nil, 0, ndDirectives, nil},
-		"$":            {NodeVariable, nil, nil, nil, 0, ndVariable, nil},
-		"...":          {NodeFragmentSpread, nil, nil, nil, 0, ndFragmentSpread, nil},
-		"[":            {NodeListValue, nil, nil, nil, 0, ndListValue, nil},
+		"query":        {NodeOperationDefinition, nil, nil, nil, nil, 0, ndOperationDefinition, nil},
+		"mutation":     {NodeOperationDefinition, nil, nil, nil, nil, 0, ndOperationDefinition, nil},
+		"subscription": {NodeOperationDefinition, nil, nil, nil, nil, 0, ndOperationDefinition, nil},
+		"fragment":     {NodeFragmentDefinition, nil, nil, nil, nil, 0, ndFragmentDefinition, nil},
+		"{":            {NodeSelectionSet, nil, nil, nil, nil, 0, ndSelectionSet, nil},
+		"(":            {NodeArguments, nil, nil, nil, nil, 0, ndArgsOrVarDef, nil},
+		"@":            {NodeDirectives, nil, nil, nil, nil, 0, ndDirectives, nil},
+		"$":            {NodeVariable, nil, nil, nil, nil, 0, ndVariable, nil},
+		"...":          {NodeFragmentSpread, nil, nil, nil, nil, 0, ndFragmentSpread, nil},
+		"[":            {NodeListValue, nil, nil, nil, nil, 0, ndListValue, nil},
 
 		// Tokens which are not part of the AST (can be retrieved by next but not be inserted by run)
 
-		"}": {"", nil, nil, nil, 0, nil, nil},
-		":": {"", nil, nil, nil, 0, nil, nil},
-		")": {"", nil, nil, nil, 0, nil, nil},
-		"=": {"", nil, nil, nil, 0, nil, nil},
-		"]": {"", nil, nil, nil, 0, nil, nil},
+		"}": {"", nil, nil, nil, nil, 0, nil, nil},
+		":": {"", nil, nil, nil, nil, 0, nil, nil},
+		")": {"", nil, nil, nil, nil, 0, nil, nil},
+		"=": {"", nil, nil, nil, nil, 0, nil, nil},
+		"]": {"", nil, nil, nil, nil, 0, nil, nil},
 	}
 	astNodeMapTokens = map[LexTokenID]*ASTNode{
-		TokenName:        {NodeName, nil, nil, nil, 0, ndTerm, nil},
-		TokenIntValue:    {NodeValue, nil, nil, nil, 0, ndTerm, nil},
-		TokenStringValue: {NodeValue, nil, nil, nil, 0, ndTerm, nil},
-		TokenFloatValue:  {NodeValue, nil, nil, nil, 0, ndTerm, nil},
-		TokenEOF:         {NodeEOF, nil, nil, nil, 0, ndTerm, nil},
+		TokenName:        {NodeName, nil, nil, nil, nil, 0, ndTerm, nil},
+		TokenIntValue:    {NodeValue, nil, nil, nil, nil, 0, ndTerm, nil},
+		TokenStringValue: {NodeValue, nil, nil, nil, nil, 0, ndTerm, nil},
+		TokenFloatValue:  {NodeValue, nil, nil, nil, nil, 0, ndTerm, nil},
+		TokenEOF:         {NodeEOF, nil, nil, nil, nil, 0, ndTerm, nil},
 	}
 }
 
@@ -64,8 +64,9 @@ Parser data structure
 type parser struct {
 	name   string          // Name to identify the input
 	node   *ASTNode        // Current ast node
-	tokens chan LexToken   // Channel which contains lex tokens
+	tokens []LexToken      // List of lex tokens
 	rp     RuntimeProvider // Runtime provider which creates runtime components
+	strict bool            // Report empty selection sets as errors
 
 	// Flags
 
@@ -85,7 +86,97 @@ ParseWithRuntime parses a given input string and returns an AST decorated with
 runtime components.
 */
 func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode, error) {
-	p := &parser{name, nil, Lex(name, input), rp, false, false}
+	return ParseWithOptions(name, input, rp, LexOptions{})
+}
+
+/*
+ParseWithOptions parses a given input string using the given lexer options and
+returns an AST decorated with runtime components. The runtime provider can be nil.
+*/
+func ParseWithOptions(name string, input string, rp RuntimeProvider, options LexOptions) (*ASTNode, error) {
+	doc, err := parse(name, input, rp, options, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+/*
+ParseStrict parses a given input string and returns an AST. Unlike Parse it
+returns an ErrEmptySelectionSet error for selection sets which contain no
+fields or fragments (e.g. { user { } }) as required by the specification.
+*/
+func ParseStrict(name string, input string) (*ASTNode, error) {
+	doc, err := parse(name, input, nil, LexOptions{}, true)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+/*
+ParsePartial parses a given input string and returns an AST. If an error
+occurs the AST contains all definitions which were parsed successfully before
+the error. This is useful for editors which need an AST of invalid input.
+*/
+func ParsePartial(name string, input string) (*ASTNode, error) {
+	doc, err := parse(name, input, nil, LexOptions{}, false)
+
+	if doc == nil {
+		doc = &ASTNode{NodeDocument, &LexToken{TokenGeneral, 0, "", 0, 0},
+			make([]*ASTNode, 0), nil, nil, 0, nil, nil}
+	}
+
+	return doc, err
+}
+
+/*
+ParseValue parses a given input string which contains a single value (e.g.
+a scalar, list, object or enum value) and returns its AST node. Trailing
+input after the value is an error.
+*/
+func ParseValue(name string, input string) (*ASTNode, error) {
+	lexer := GetLexer()
+	defer PutLexer(lexer)
+
+	lexer.Reset(name, input)
+
+	p := &parser{name, nil, lexer.Lex(), nil, false, false, false}
+
+	node, err := p.next()
+
+	if err == nil {
+		p.node = node
+
+		if node, err = parseValue(p); err == nil && p.node.Name != NodeEOF {
+			err = p.newParserError(ErrUnexpectedToken, p.node.Token.String(), *p.node.Token)
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	SetParents(node)
+
+	return node, nil
+}
+
+/*
+parse parses a given input string and returns an AST. Returns the partially
+parsed AST if an error occurs.
+*/
+func parse(name string, input string, rp RuntimeProvider, options LexOptions, strict bool) (*ASTNode, error) {
+	lexer := GetLexer()
+	defer PutLexer(lexer)
+
+	lexer.ResetWithOptions(name, input, options)
+
+	p := &parser{name, nil, lexer.Lex(), rp, strict, false, false}
 
 	node, err := p.next()
 
@@ -114,7 +205,9 @@ func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode,
 
 				} else {
 
-					return nil, p.newParserError(ErrMultipleShorthand,
+					SetParents(doc)
+
+					return doc, p.newParserError(ErrMultipleShorthand,
 						node.Token.String(), *node.Token)
 				}
 			} else {
@@ -126,11 +219,9 @@ func ParseWithRuntime(name string, input string, rp RuntimeProvider) (*ASTNode,
 		}
 	}
 
-	if err == nil {
-		return doc, nil
-	}
+	SetParents(doc)
 
-	return nil, err
+	return doc, err
 }
 
 /*
@@ -172,7 +263,13 @@ next retrieves the next lexer token and return it as ASTNode.
 */
 func (p *parser) next() (*ASTNode, error) {
 
-	token, more := <-p.tokens
+	var token LexToken
+
+	more := len(p.tokens) > 0
+	if more {
+		token = p.tokens[0]
+		p.tokens = p.tokens[1:]
+	}
 
 	if !more {
 
@@ -184,7 +281,7 @@ func (p *parser) next() (*ASTNode, error) {
 
 		// There was a lexer error wrap it in a parser error
 
-		return nil, p.newParserError(ErrLexicalError, token.Val, token)
+		return nil, lexerError(p.name, token)
 
 	} else if node, ok := astNodeMapValues[token.Val]; ok &&
 		(!p.isValue || token.ID == TokenPunctuator) && token.ID != TokenStringValue {
@@ -250,7 +347,8 @@ func ndListValue(p *parser, self *ASTNode) (*ASTNode, error) {
 }
 
 /*
-ndInputObject parses an input object literal. (@spec 2.9.8)
+ndInputObject parses an input object literal. (@spec 2.9.8) The ObjectField
+children are kept in source order.
 */
 func ndInputObject(p *parser, self *ASTNode) (*ASTNode, error) {
 	var current *ASTNode
@@ -540,6 +638,10 @@ func ndSelectionSet(p *parser, self *ASTNode) (*ASTNode, error) {
 		}
 	}
 
+	if p.strict && len(self.Children) == 0 {
+		return nil, p.newParserError(ErrEmptySelectionSet, "", *self.Token)
+	}
+
 	return self, skipToken(p, "}")
 }
 
@@ -731,18 +833,17 @@ func parseValue(p *parser) (*ASTNode, error) {
 
 			changeAstNode(current, NodeEnumValue, p)
 
-		} else {
+		} else if current.Name != NodeVariable &&
+			current.Name != NodeListValue &&
+			current.Name != NodeObjectValue {
 
 			// Everything else must be a variable or a complex data type
 
-			errorutil.AssertTrue(current.Name == NodeVariable ||
-				current.Name == NodeListValue ||
-				current.Name == NodeObjectValue, fmt.Sprint("Unexpected value node:", current))
+			return nil, p.newParserError(ErrValueOrVariableExpected,
+				current.Token.String(), *current.Token)
 		}
 
-		if err == nil {
-			return current, err
-		}
+		return current, nil
 	}
 
 	return nil, err
@@ -759,9 +860,17 @@ func ndDirectives(p *parser, self *ASTNode) (*ASTNode, error) {
 
 	if err = acceptChild(p, dir, TokenName); err == nil {
 
-		if current, err = p.run(0); err == nil {
+		if p.node.Token.Val == "(" {
+
+			// Parse arguments - directives can be used without arguments
+
+			if current, err = p.run(0); err == nil {
+				dir.Children = append(dir.Children, current)
+			}
+		}
+
+		if err == nil {
 
-			dir.Children = append(dir.Children, current)
 			self.Children = append(self.Children, dir)
 
 			if p.node.Token.Val == "@" {
@@ -12,6 +12,7 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -442,21 +443,16 @@ func TestParserErrors(t *testing.T) {
 		return
 	}
 
-	tokens := make(chan LexToken, 1)
-	close(tokens)
-	p := &parser{"test", nil, tokens, nil, false, false}
+	p := &parser{"test", nil, nil, nil, false, false, false}
 
 	if _, err := p.next(); err == nil || err.Error() != "Parse error in test: Unexpected end (Line:0 Pos:0)" {
 		t.Error(err)
 		return
 	}
 
-	tokens = make(chan LexToken, 1)
-	tokens <- LexToken{-1, 0, "foo", 0, 0}
-	close(tokens)
-	p = &parser{"test", nil, tokens, nil, false, false}
+	p = &parser{"test", nil, []LexToken{{-1, 0, "foo", 0, 0}}, nil, false, false, false}
 
-	if _, err := p.next(); err == nil || err.Error() != `Parse error in test: Unknown term (id:-1 (foo)) (Line:0 Pos:0)` {
+	if _, err := p.next(); err == nil || err.Error() != `Parse error in test: Unknown term (id:LexTokenID(-1) (foo)) (Line:0 Pos:0)` {
 		t.Error(err)
 		return
 	}
@@ -1234,3 +1230,597 @@ fragment foo on bar
 		return
 	}
 }
+
+func TestDirectivesWithoutArgumentsParsing(t *testing.T) {
+
+	input := `query q @foo { x @a @b(c: 1) @d y }`
+	expectedOutput := `
+Document
+  ExecutableDefinition
+    OperationDefinition
+      OperationType: query
+      Name: q
+      Directives
+        Directive
+          Name: foo
+      SelectionSet
+        Field
+          Name: x
+          Directives
+            Directive
+              Name: a
+            Directive
+              Name: b
+              Arguments
+                Argument
+                  Name: c
+                  Value: 1
+            Directive
+              Name: d
+        Field
+          Name: y
+`[1:]
+
+	if res, err := Parse("mytest", input); err != nil || fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput, "Error:", err)
+		return
+	}
+}
+
+func TestDirectiveLookup(t *testing.T) {
+
+	input := `{
+  oldField @deprecated(reason: "Use newField") @include(if: true)
+}`
+
+	res, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	field := res.Children[0].Children[0].Children[0].Children[0]
+
+	if d, ok := field.Directive("deprecated"); !ok || d.Children[0].Token.Val != "deprecated" {
+		t.Error("Unexpected result:", d, ok)
+		return
+	}
+
+	if v, ok := field.DirectiveArg("deprecated", "reason"); !ok || v != "Use newField" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := field.DirectiveArg("include", "if"); !ok || v != "true" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := field.DirectiveArg("deprecated", "foo"); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if d, ok := field.Directive("skip"); ok {
+		t.Error("Unexpected result:", d, ok)
+		return
+	}
+
+	if v, ok := field.DirectiveArg("skip", "if"); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+}
+
+func TestASTFromJSON(t *testing.T) {
+
+	ast, err := Parse("mytest", `query q($id: Int = 1) { user(id: $id) { name ...f @skip(if: false) } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data, err := json.Marshal(ast.Plain())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ast2, err := ASTFromJSON(data)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+
+	if res, err := ASTFromJSON([]byte(`{"name": "Document", "children": [{}]}`)); err == nil ||
+		err.Error() != "Found plain ast node without a name: map[]" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ASTFromJSON([]byte(`[1, 2]`)); err == nil ||
+		!strings.HasPrefix(err.Error(), "Could not decode plain ast: json: cannot unmarshal array") {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestPlainGet(t *testing.T) {
+
+	res, err := Parse("mytest", `{ foo { bar } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	plain := res.Plain()
+
+	if v, ok := PlainGet(plain, 0, 0, 0, 0, 1, 0, 0, "value"); !ok || v != "bar" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := PlainGet(plain, "name"); !ok || v != "Document" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	// Plain ASTs which were decoded from JSON use []interface{} lists
+
+	var jsonPlain map[string]interface{}
+	b, _ := json.Marshal(plain)
+	json.Unmarshal(b, &jsonPlain)
+
+	if v, ok := PlainGet(jsonPlain, 0, 0, 0, 0, 1, 0, 0, "value"); !ok || v != "bar" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := PlainGet(plain, 0, 5); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := PlainGet(plain, "foo"); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := PlainGet(plain, "name", "foo"); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := PlainGet(plain, 1.5); ok {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+}
+
+func TestFindField(t *testing.T) {
+
+	res, err := Parse("mytest", `{ name me : user { id } ...frag }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	selectionSet := res.Children[0].Children[0].Children[0]
+
+	if f := FindField(selectionSet, "me"); f == nil || f.Children[1].Token.Val != "user" {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if f := FindField(selectionSet, "name"); f == nil || f.Children[0].Token.Val != "name" {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if !HasField(selectionSet, "me") || !HasField(selectionSet, "name") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	// Aliased fields are not found by their name and nested fields are not found
+
+	for _, key := range []string{"user", "id", "frag", "foo"} {
+		if f := FindField(selectionSet, key); f != nil || HasField(selectionSet, key) {
+			t.Error("Unexpected result:", key, f)
+			return
+		}
+	}
+}
+
+func TestObjectField(t *testing.T) {
+
+	value, err := ParseValue("mytest", `{ name: "foo", filter: { age: 42, tags: ["a"] }, name: "bar" }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res := value.Children[0].Token.Val + " " + value.Children[1].Token.Val + " " + value.Children[2].Token.Val; res !=
+		"name filter name" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if v, ok := ObjectField(value, "name"); !ok || v.Token.Val != "foo" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	filter, ok := ObjectField(value, "filter")
+	if !ok || filter.Name != NodeObjectValue {
+		t.Error("Unexpected result:", filter, ok)
+		return
+	}
+
+	if v, ok := ObjectField(filter, "age"); !ok || v.Token.Val != "42" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := ObjectField(filter, "tags"); !ok || v.Name != NodeListValue {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := ObjectField(value, "age"); ok || v != nil {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	if v, ok := ObjectField(filter.Children[0].Children[0], "age"); ok || v != nil {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+}
+
+func TestFindAll(t *testing.T) {
+
+	res, err := Parse("mytest", `query q($id: Int) {
+  user(id: $id, type: ADMIN) @include(if: true) {
+    friends(first: 10) { id name }
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if args := res.FindAll(NodeArgument); len(args) != 4 ||
+		args[0].Children[0].Token.Val != "id" || args[1].Children[0].Token.Val != "type" ||
+		args[2].Children[0].Token.Val != "if" || args[3].Children[0].Token.Val != "first" {
+		t.Error("Unexpected result:", args)
+		return
+	}
+
+	if fields := res.FindAll(NodeField); len(fields) != 4 {
+		t.Error("Unexpected result:", fields)
+		return
+	}
+
+	if f := res.FindFirst(NodeField); f == nil || f.Children[0].Token.Val != "user" {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if f := res.FindFirst(NodeVariable); f == nil || f.Token.Val != "id" {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if f := res.FindFirst(NodeFragmentSpread); f != nil {
+		t.Error("Unexpected result:", f)
+		return
+	}
+
+	if res := res.FindAll(NodeFragmentSpread); res != nil {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestParseValue(t *testing.T) {
+
+	res, err := ParseValue("mytest", `{name: "foo", tags: [A, B], nested: {id: $id, score: 1.5}}`)
+	if err != nil || res.String() != `
+ObjectValue
+  ObjectField: name
+    Value: foo
+  ObjectField: tags
+    ListValue
+      EnumValue: A
+      EnumValue: B
+  ObjectField: nested
+    ObjectValue
+      ObjectField: id
+        Variable: id
+      ObjectField: score
+        Value: 1.5
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = ParseValue("mytest", `[1, "two", true, null, [3]]`)
+	if err != nil || res.String() != `
+ListValue
+  Value: 1
+  Value: two
+  Value: true
+  Value: null
+  ListValue
+    Value: 3
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseValue("mytest", `FOO`); err != nil || res.String() != "EnumValue: FOO\n" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseValue("mytest", `[1, 2] 3`); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected term (int(3)) (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseValue("mytest", `[1, 2`); err == nil || err.Error() !=
+		"Parse error in mytest: Unexpected end (Line:1 Pos:5)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Invalid values result in an error
+
+	if res, err := ParseValue("mytest", `[(]`); err == nil || err.Error() !=
+		"Parse error in mytest: Term cannot start an expression (Line:1 Pos:3)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := Parse("mytest", `{ a(x: ( ) }`); err == nil || err.Error() !=
+		"Parse error in mytest: Value or variable expected ()) (Line:1 Pos:10)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestNodePath(t *testing.T) {
+
+	ast, err := Parse("mytest", `query q {
+  user(id: 1) {
+    name
+    friends { id }
+  }
+}
+fragment f on User { id }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	friends := FindField(FindField(ast.FindFirst(NodeSelectionSet), "user").FindFirst(NodeSelectionSet), "friends")
+
+	if res := friends.Path(); res != "Document/ExecutableDefinition[0]/OperationDefinition/"+
+		"SelectionSet/Field/SelectionSet/Field[1]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := friends.FindFirst(NodeField).FindFirst(NodeName).Path(); res != "Document/ExecutableDefinition[0]/"+
+		"OperationDefinition/SelectionSet/Field/SelectionSet/Field[1]/SelectionSet/Field/Name" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.FindAll(NodeField)[4].Path(); res != "Document/ExecutableDefinition[1]/FragmentDefinition/"+
+		"SelectionSet/Field" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ast.Path(); res != "Document" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Copies keep the path information
+
+	if res := ast.Clone().FindAll(NodeField)[4].Path(); res != "Document/ExecutableDefinition[1]/FragmentDefinition/"+
+		"SelectionSet/Field" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestParentLinks(t *testing.T) {
+	var checkParents func(n *ASTNode) error
+
+	checkParents = func(n *ASTNode) error {
+		for _, child := range n.Children {
+			if child.Parent != n {
+				return fmt.Errorf("Unexpected parent of %v: %v", child.Name, child.Parent)
+			}
+			if err := checkParents(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ast, err := Parse("mytest", `query q($id: Int) { user(id: $id) { name ...f } } fragment f on User { id }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := checkParents(ast); err != nil || ast.Parent != nil {
+		t.Error(err)
+		return
+	}
+
+	if f := ast.FindFirst(NodeFragmentSpread); f.Parent.Name != NodeSelectionSet ||
+		f.Parent.Parent.Name != NodeField || f.Parent.Parent.Children[0].Token.Val != "user" {
+		t.Error("Unexpected result:", f.Parent)
+		return
+	}
+
+	// Clones have their own parent links
+
+	field := ast.FindFirst(NodeField)
+	clone := field.Clone()
+
+	if err := checkParents(clone); err != nil || clone.Parent != nil || clone.Children[1].Parent == field {
+		t.Error("Unexpected result:", clone, err)
+		return
+	}
+
+	// ASTs from plain ASTs have parent links
+
+	ast2, err := ASTFromPlain(ast.Plain())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := checkParents(ast2); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Parent links are not serialized
+
+	if _, err := json.Marshal(ast); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Parents of externally built ASTs can be set
+
+	name := &ASTNode{Name: NodeName, Token: &LexToken{Val: "foo"}}
+	selectionSet := &ASTNode{Name: NodeSelectionSet, Token: &LexToken{},
+		Children: []*ASTNode{{Name: NodeField, Token: &LexToken{}, Children: []*ASTNode{name}}}}
+
+	SetParents(selectionSet)
+
+	if err := checkParents(selectionSet); err != nil || name.Path() != "SelectionSet/Field/Name" {
+		t.Error("Unexpected result:", name.Path(), err)
+		return
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+
+	if _, err := ParseStrict("mytest", `{ user { } }`); err == nil ||
+		err.Error() != "Parse error in mytest: Empty selection set (Line:1 Pos:8)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ParseStrict("mytest", "query foo {\n}"); err == nil ||
+		err.Error() != "Parse error in mytest: Empty selection set (Line:1 Pos:11)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := ParseStrict("mytest", `fragment f on User { ... on Admin { } }`); err == nil ||
+		err.Error() != "Parse error in mytest: Empty selection set (Line:1 Pos:35)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	// Empty input objects are allowed
+
+	ast, err := ParseStrict("mytest", `{ user(filter: {}) { ...f } }`)
+	if err != nil || ast == nil {
+		t.Error("Unexpected result:", ast, err)
+		return
+	}
+
+	// The default is lenient
+
+	if _, err := Parse("mytest", `{ user { } }`); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+
+	input := `query a { foo }
+query b { bar(x: 1) }
+query c { baz(
+`
+	expectedOutput := `
+Document
+  ExecutableDefinition
+    OperationDefinition
+      OperationType: query
+      Name: a
+      SelectionSet
+        Field
+          Name: foo
+  ExecutableDefinition
+    OperationDefinition
+      OperationType: query
+      Name: b
+      SelectionSet
+        Field
+          Name: bar
+          Arguments
+            Argument
+              Name: x
+              Value: 1
+`[1:]
+
+	res, err := ParsePartial("mytest", input)
+	if err == nil || err.Error() != "Parse error in mytest: Unexpected end (Line:4 Pos:1)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if fmt.Sprint(res) != expectedOutput {
+		t.Error("Unexpected parser output:\n", res, "expected was:\n", expectedOutput)
+		return
+	}
+
+	if res, err := Parse("mytest", input); res != nil || err == nil {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParsePartial("mytest", `{ a } { b }`); err == nil || fmt.Sprint(res) != `
+Document
+  ExecutableDefinition
+    OperationDefinition
+      SelectionSet
+        Field
+          Name: a
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParsePartial("mytest", `"""`); err == nil || fmt.Sprint(res) != "Document\n" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParsePartial("mytest", `{ a }`); err != nil || fmt.Sprint(res) != `
+Document
+  ExecutableDefinition
+    OperationDefinition
+      SelectionSet
+        Field
+          Name: a
+`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
@@ -21,6 +21,28 @@ func (p *parser) newParserError(t error, d string, token LexToken) error {
 	return &Error{p.name, t, d, token.Lline, token.Lpos}
 }
 
+/*
+newASTError creates a new ParserError object for an error which was found in
+an existing AST.
+*/
+func newASTError(t error, d string, node *ASTNode) error {
+	return &Error{"AST", t, d, node.Token.Lline, node.Token.Lpos}
+}
+
+/*
+lexerError creates a new ParserError object for a given lexer error token.
+*/
+func lexerError(name string, token LexToken) error {
+
+	if token.Val == ErrUnexpectedBOM.Error() {
+		return &Error{name, ErrUnexpectedBOM, "", token.Lline, token.Lpos}
+	} else if token.Val == ErrStringTooLong.Error() {
+		return &Error{name, ErrStringTooLong, "", token.Lline, token.Lpos}
+	}
+
+	return &Error{name, ErrLexicalError, token.Val, token.Lline, token.Lpos}
+}
+
 /*
 Error models a parser related error
 */
@@ -51,16 +73,30 @@ func (pe *Error) Error() string {
 Parser related error types
 */
 var (
+	ErrCannotCoerce             = errors.New("Cannot coerce value")
+	ErrCyclicFragment           = errors.New("Cyclic fragment reference")
+	ErrDuplicateArgument        = errors.New("Duplicate argument")
+	ErrDuplicateDefinition      = errors.New("Duplicate definition")
+	ErrDuplicateVariable        = errors.New("Duplicate variable")
+	ErrEmptySelectionSet        = errors.New("Empty selection set")
+	ErrFieldConflict            = errors.New("Conflicting fields")
+	ErrFieldNotAllowed          = errors.New("Field not allowed")
 	ErrImpossibleLeftDenotation = errors.New("Term can only start an expression")
 	ErrImpossibleNullDenotation = errors.New("Term cannot start an expression")
+	ErrInvalidEnumValue         = errors.New("Invalid enum value")
 	ErrLexicalError             = errors.New("Lexical error")
 	ErrNameExpected             = errors.New("Name expected")
 	ErrOnExpected               = errors.New("Type condition starting with 'on' expected")
 	ErrSelectionSetExpected     = errors.New("Selection Set expected")
 	ErrMultipleShorthand        = errors.New("Query shorthand only allowed for one query operation")
+	ErrStringTooLong            = errors.New("String value too long")
+	ErrUndefinedVariable        = errors.New("Undefined variable")
+	ErrUnexpectedBOM            = errors.New("Unexpected byte order mark")
 	ErrUnexpectedEnd            = errors.New("Unexpected end")
 	ErrUnexpectedToken          = errors.New("Unexpected term")
+	ErrUnknownFragment          = errors.New("Unknown fragment")
 	ErrUnknownToken             = errors.New("Unknown term")
+	ErrUnusedVariable           = errors.New("Unused variable")
 	ErrValueOrVariableExpected  = errors.New("Value or variable expected")
 	ErrVariableExpected         = errors.New("Variable expected")
 )
@@ -59,28 +59,63 @@ var prettyPrinterMap = map[string]*template.Template{
 
 /*
 PrettyPrint produces a pretty printed EQL query from a given AST.
+
+The printer visits all nodes in the order of their children and never
+reorders them. For a parsed AST this means selections, arguments, object
+fields, list values and directives are printed in their source order and
+parsing and printing the output of PrettyPrint again produces a
+byte-identical result (see TestPrettyPrintStable).
 */
 func PrettyPrint(ast *ASTNode) (string, error) {
-	var visit func(ast *ASTNode, path []*ASTNode) (string, error)
+	return PrettyPrintWithOptions(ast, PrettyPrintOptions{})
+}
 
-	quoteValue := func(val string, allowNonQuotation bool) string {
+/*
+PrettyPrintExpanded produces a pretty printed EQL query from a given AST where
+each argument and each object field is placed on its own line. This output is
+best suited for version control since changes produce minimal diffs.
+*/
+func PrettyPrintExpanded(ast *ASTNode) (string, error) {
+	return PrettyPrintWithOptions(ast, PrettyPrintOptions{Expanded: true})
+}
 
-		if val == "" {
-			return `""`
-		}
+/*
+PrettyPrintCompact produces a pretty printed EQL query from a given AST on a
+single line. This output is best suited for sending queries over the wire.
+*/
+func PrettyPrintCompact(ast *ASTNode) (string, error) {
+	return PrettyPrintWithOptions(ast, PrettyPrintOptions{Compact: true})
+}
 
-		isNumber, _ := regexp.MatchString("^[0-9][0-9\\.e-+]*$", val)
-		isInlineString, _ := regexp.MatchString("^[a-zA-Z0-9_:.]*$", val)
+/*
+PrettyPrintOptions are options which change the output of the pretty printer.
+*/
+type PrettyPrintOptions struct {
+	CanonicalFloats   bool // Print float values in canonical exponent form (e.g. 1.5e+10)
+	ArgumentWrapWidth int  // Put field arguments on separate lines if a line would be longer (0 disables wrapping)
+	Expanded          bool // Always put each argument and each object field on its own line
+	Compact           bool // Print everything on a single line (ArgumentWrapWidth and Expanded are ignored)
+}
 
-		if allowNonQuotation && (isNumber || isInlineString) {
-			return val
-		} else if strings.ContainsRune(val, '"') {
-			val = strings.Replace(val, "\"", "\\\"", -1)
-		}
-		if strings.Contains(val, "\n") {
+/*
+PrettyPrintWithOptions produces a pretty printed EQL query from a given AST
+using the given options.
+*/
+func PrettyPrintWithOptions(ast *ASTNode, options PrettyPrintOptions) (string, error) {
+	var visit func(ast *ASTNode, path []*ASTNode) (string, error)
+
+	if options.Compact {
+		options.ArgumentWrapWidth = 0
+		options.Expanded = false
+	}
+
+	quoteValue := func(val string, path []*ASTNode) string {
+
+		if ppBlockStringAllowed(path, options) && ShouldUseBlockString(val) {
 			return fmt.Sprintf("\"\"\"%v\"\"\"", val)
 		}
-		return fmt.Sprintf("\"%v\"", val)
+
+		return EscapeString(val)
 	}
 
 	visit = func(ast *ASTNode, path []*ASTNode) (string, error) {
@@ -90,24 +125,47 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 		if ast.Name == NodeValue {
 			v := ast.Token.Val
 
-			_, err := strconv.ParseFloat(v, 32)
-			isNum := err == nil
+			if options.CanonicalFloats && ast.Token.ID == TokenFloatValue {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					return canonicalFloat(f), nil
+				}
+			}
+
+			// String values are always quoted - all other values (numbers,
+			// booleans and null) are printed as they are. Values without a
+			// token type (e.g. from a plain AST) are quoted unless they are
+			// a valid number, boolean or null literal.
 
-			isConst := stringutil.IndexOf(v, []string{
-				"true", "false", "null",
-			}) != -1
+			if ast.Token.ID == TokenStringValue ||
+				ast.Token.ID == TokenGeneral && !untypedLiteralPattern.MatchString(v) {
+				return quoteValue(v, path), nil
+			}
 
-			return quoteValue(ast.Token.Val, isConst || isNum), nil
+			return v, nil
 
 		} else if ast.Name == NodeVariable {
 			return fmt.Sprintf("$%v", ast.Token.Val), nil
 		} else if ast.Name == NodeAlias {
 			return fmt.Sprintf("%v :", ast.Token.Val), nil
 		} else if ast.Name == NodeFragmentSpread {
-			return ppPostProcessing(ast, path, fmt.Sprintf("...%v\n", ast.Token.Val)), nil
+			var directives string
+
+			for _, child := range ast.Children {
+				res, err := visit(child, append(path, child))
+				if err != nil {
+					return "", err
+				}
+
+				directives += " " + res
+			}
+
+			return ppPostProcessing(ast, path, fmt.Sprintf("...%v%v\n", ast.Token.Val, directives)), nil
 		} else if ast.Name == NodeTypeCondition {
 			return fmt.Sprintf("on %v", ast.Token.Val), nil
 		} else if ast.Name == NodeDefaultValue {
+			if ast.Token.ID == TokenStringValue {
+				return fmt.Sprintf("=%v", quoteValue(ast.Token.Val, path)), nil
+			}
 			return fmt.Sprintf("=%v", ast.Token.Val), nil
 		}
 
@@ -167,6 +225,15 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 			}
 			buf.WriteString(")")
 
+			if width, ok := ppArgumentsLineWidth(path); options.Expanded || ok && options.ArgumentWrapWidth > 0 &&
+				width+stringutil.DisplayWidth(buf.String()) > options.ArgumentWrapWidth {
+
+				// Put each argument on its own indented line
+
+				buf.Reset()
+				buf.WriteString(ppExpandedList("(", ")", children))
+			}
+
 			return ppPostProcessing(ast, path, buf.String()), nil
 
 		} else if ast.Name == NodeListValue {
@@ -212,6 +279,13 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 		} else if ast.Name == NodeObjectValue {
 
+			if options.Expanded && children != nil {
+
+				// Put each object field on its own indented line
+
+				return ppExpandedList("{", "}", children), nil
+			}
+
 			buf.WriteString("{")
 
 			if children != nil {
@@ -284,9 +358,263 @@ func PrettyPrint(ast *ASTNode) (string, error) {
 
 	res, err := visit(ast, []*ASTNode{ast})
 
+	if err == nil && options.Compact {
+
+		// String values contain no raw newlines in compact mode so all lines
+		// can be joined
+
+		var lines []string
+
+		for _, line := range strings.Split(res, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		res = strings.Join(lines, " ")
+	}
+
 	return strings.TrimSpace(res), err
 }
 
+/*
+untypedLiteralPattern matches values of value nodes without a token type
+which are printed without quotes (Int, Float, Boolean and Null literals).
+*/
+var untypedLiteralPattern = regexp.MustCompile(
+	`^(true|false|null|-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?)$`)
+
+/*
+canonicalFloat formats a given float in exponent notation with the shortest
+mantissa and an exponent without leading zeros (e.g. 1.5e+10 or 5e-1).
+*/
+func canonicalFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'e', -1, 64)
+
+	// Remove the zero padding of the exponent (e.g. 5e-01 becomes 5e-1)
+
+	i := strings.IndexByte(s, 'e') + 2
+	exp := strings.TrimLeft(s[i:], "0")
+
+	if exp == "" {
+		exp = "0"
+	}
+
+	return s[:i] + exp
+}
+
+/*
+EscapeString returns a given string as a double-quoted GraphQL string literal.
+Quotes, backslashes and control characters are escaped. (@spec 2.9.4)
+*/
+func EscapeString(s string) string {
+	var buf bytes.Buffer
+
+	buf.WriteRune('"')
+
+	for _, r := range s {
+
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				buf.WriteString(fmt.Sprintf(`\u%04x`, r))
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	buf.WriteRune('"')
+
+	return buf.String()
+}
+
+/*
+ShouldUseBlockString checks if a given string value can be written as a block
+string without changing its value. This is the case for multi-line values
+which:
+
+- do not start or end with whitespace (block strings remove blank initial and
+trailing lines and uniform indentation)
+- contain no line with trailing whitespace (whitespace-only lines are emptied)
+- contain no carriage returns (block strings normalize line endings)
+- contain no triple quotes and do not end with a quote or a backslash
+
+The pretty printer only writes block strings in positions which it does not
+indent (e.g. default values of variables).
+*/
+func ShouldUseBlockString(s string) bool {
+
+	if !strings.Contains(s, "\n") || strings.ContainsRune(s, '\r') || strings.TrimSpace(s) != s ||
+		strings.Contains(s, `"""`) || strings.HasSuffix(s, `"`) || strings.HasSuffix(s, `\`) {
+		return false
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimRightFunc(line, unicode.IsSpace) != line {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+Format parses a given input string and returns it pretty printed. Formatting
+already formatted input returns the same text. If the input cannot be parsed
+the returned error contains the offending line and a marker at the position
+of the error.
+*/
+func Format(name string, input string) (string, error) {
+
+	ast, err := Parse(name, input)
+
+	if err != nil {
+		if perr, ok := err.(*Error); ok {
+			err = fmt.Errorf("%v\n%v", err, errorContext(input, perr.Line, perr.Pos))
+		}
+
+		return "", err
+	}
+
+	return PrettyPrint(ast)
+}
+
+/*
+errorContext returns the line of a given input and a marker line which points
+to the given position.
+*/
+func errorContext(input string, line int, pos int) string {
+	lines := strings.Split(input, "\n")
+
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+
+	text := strings.TrimRight(lines[line-1], "\r")
+
+	// Positions are counted from the last newline character - on the first
+	// line there is no such character
+
+	offset := pos - 1
+	if line > 1 {
+		offset--
+	}
+
+	if offset < 0 {
+		offset = 0
+	} else if offset > len(text) {
+		offset = len(text)
+	}
+
+	// Keep tabs in the marker line so it lines up with the input line
+
+	var marker bytes.Buffer
+
+	for _, r := range text[:offset] {
+		if r == '\t' {
+			marker.WriteRune(r)
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	marker.WriteRune('^')
+
+	return fmt.Sprintf("%v\n%v", text, marker.String())
+}
+
+/*
+ppArgumentsLineWidth returns the width of the line in front of an arguments
+node which is the last element of a given path. Returns false if the arguments
+do not belong to a field.
+*/
+func ppArgumentsLineWidth(path []*ASTNode) (int, bool) {
+	var width int
+
+	if len(path) < 2 || path[len(path)-2].Name != NodeField {
+		return 0, false
+	}
+
+	// Each selection set adds one level of indentation
+
+	for _, n := range path {
+		if n.Name == NodeSelectionSet {
+			width += IndentationLevel
+		}
+	}
+
+	// Add alias and name of the field
+
+	for _, n := range path[len(path)-2].Children {
+		if n.Name == NodeAlias {
+			width += len(n.Token.Val) + 3
+		} else if n.Name == NodeName {
+			width += len(n.Token.Val)
+		}
+	}
+
+	return width, true
+}
+
+/*
+ppExpandedList writes a list of pretty printed children with each child on
+its own indented line between given opening and closing brackets.
+*/
+func ppExpandedList(open string, close string, children map[string]string) string {
+	var buf bytes.Buffer
+
+	indentSpaces := stringutil.GenerateRollingString(" ", IndentationLevel)
+
+	buf.WriteString(open)
+	buf.WriteString("\n")
+	for i := 1; i <= len(children); i++ {
+		buf.WriteString(indentSpaces)
+		buf.WriteString(strings.ReplaceAll(children[fmt.Sprint("c", i)], "\n", "\n"+indentSpaces))
+		if i < len(children) {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(close)
+
+	return buf.String()
+}
+
+/*
+ppBlockStringAllowed checks if a value at a given path can be written as a
+block string. The lines of fields, fragment spreads, inline fragments and
+expanded lists are indented by the pretty printer which would change the
+value of a block string. Compact output must not contain newlines.
+*/
+func ppBlockStringAllowed(path []*ASTNode, options PrettyPrintOptions) bool {
+
+	if options.Expanded || options.Compact {
+		return false
+	}
+
+	for _, n := range path {
+		if n.Name == NodeField || n.Name == NodeFragmentSpread || n.Name == NodeInlineFragment {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
 ppPostProcessing applies post processing rules.
 */
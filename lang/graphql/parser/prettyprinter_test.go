@@ -112,12 +112,10 @@ Yours,
 		return
 	}
 
-	ppOutput := `{
-  foo(bar: """Hello,
-    World!
+	// Block strings in indented positions would change their value
 
-  Yours,
-    GraphQL.""")
+	ppOutput := `{
+  foo(bar: "Hello,\n  World!\n\nYours,\n  GraphQL.")
 }`
 
 	ppres, err := PrettyPrint(astres)
@@ -127,6 +125,11 @@ Yours,
 		return
 	}
 
+	if ast, err := Parse("mytest", ppres); err != nil || fmt.Sprint(ast) != expectedOutput {
+		t.Error("Unexpected result:", ast, err)
+		return
+	}
+
 	val := astres.Children[0].Children[0].Children[0].Children[0].Children[1].Children[0].Children[1].Token.Val
 	if val != "Hello,\n  World!\n\nYours,\n  GraphQL." {
 		t.Error("Unexpected result:", val)
@@ -390,6 +393,37 @@ Document
 		return
 	}
 
+	input = `
+{
+  user {
+    ...userFields @include(if: $details)
+  }
+}`[1:]
+
+	expectedOutput = `
+Document
+  ExecutableDefinition
+    OperationDefinition
+      SelectionSet
+        Field
+          Name: user
+          SelectionSet
+            FragmentSpread: userFields
+              Directives
+                Directive
+                  Name: include
+                  Arguments
+                    Argument
+                      Name: if
+                      Variable: details
+`[1:]
+
+	if err := testPrettyPrinting(input, expectedOutput,
+		input); err != nil {
+		t.Error(err)
+		return
+	}
+
 	input = `
 query inlineFragmentTyping {
   profiles(handles: ["zuck", "cocacola"]) {
@@ -494,6 +528,466 @@ Document
 	}
 }
 
+func TestPrettyPrintStable(t *testing.T) {
+
+	// The printer keeps the order of the AST - scrambled arguments, object
+	// fields, list values, directives and selections are kept as they are
+
+	input := `
+query q ($z: Int=1, $a: String) @b @a(z: 1, a: 2) {
+  f(z: 1, a: 2, m: {z : 1, a : [3, 1, 2]}) @skip(if: $z) @include(if: true) {
+    z
+    a : y
+    ...zFrag @b @a
+    ... on A @z {
+      b
+    }
+  }
+  x @foo @bar
+  a
+}`[1:]
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ppres, err := PrettyPrint(ast)
+	if err != nil || ppres != input {
+		t.Error("Unexpected result:", ppres, err)
+		return
+	}
+
+	ast, err = Parse("mytest", ppres)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ppres2, err := PrettyPrint(ast); err != nil || ppres2 != ppres {
+		t.Error("Unexpected result:", ppres2, err)
+		return
+	}
+}
+
+func TestPrettyPrintBlockStringQuotes(t *testing.T) {
+
+	// Default values of variables are not indented by the pretty printer
+
+	input := `query q ($a: String="""say "hi" \""" here
+next line""", $b: String="ends with a\nquote\"") {
+  foo
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	values := ast.FindAll(NodeDefaultValue)
+
+	if res := fmt.Sprintf("%q %q", values[0].Token.Val, values[1].Token.Val); res !=
+		`"say \"hi\" \"\"\" here\nnext line" "ends with a\nquote\""` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Values which contain triple quotes are written as normal strings
+
+	ppres, err := PrettyPrint(ast)
+	if err != nil || ppres != `query q ($a: String="say \"hi\" \"\"\" here\nnext line", $b: String="ends with a\nquote\"") {
+  foo
+}` {
+		t.Error("Unexpected result:", ppres, err)
+		return
+	}
+
+	ast2, err := Parse("mytest", ppres)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+}
+
+func TestShouldUseBlockString(t *testing.T) {
+	testdata := []string{
+		"Hello,\n  World!\n\nYours,\n  GraphQL.",
+		"single line",
+		"",
+		"a \"quoted\"\nvalue",
+		"contains\n\"\"\" quotes",
+		"contains\n\"\"\"\" four quotes",
+		"  indented\nlines",
+		"\nleading newline",
+		"trailing newline\n",
+		"ends with\na quote\"",
+		"ends with\na backslash\\",
+		"escaped\n\\\"\"\" quotes",
+		"a\n   \nb",
+		"trailing \nspace",
+		"a\r\nb",
+		"a\rb\nc",
+		"tab\tinside\nvalue",
+	}
+	expected := []bool{true, false, false, true, false, false, false, false, false, false, false, false,
+		false, false, false, false, true}
+
+	for i, str := range testdata {
+		if res := ShouldUseBlockString(str); res != expected[i] {
+			t.Error("Unexpected result:", fmt.Sprintf("%q", str), res)
+			return
+		}
+
+		// Printing and parsing the value must not change it - neither in an
+		// unindented position (variable default) nor in an indented position
+		// (field argument)
+
+		ast, err := Parse("mytest", fmt.Sprintf(`query q ($a: String=%v) { foo { bar(a: %v) } }`,
+			EscapeString(str), EscapeString(str)))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		ppres, _ := PrettyPrint(ast)
+
+		if strings.Contains(ppres, `String="""`) != expected[i] {
+			t.Error("Unexpected result:", ppres)
+			return
+		}
+
+		ast, err = Parse("mytest", ppres)
+		if err != nil || ast.FindFirst(NodeDefaultValue).Token.Val != str ||
+			ast.FindFirst(NodeArgument).Children[1].Token.Val != str {
+			t.Error("Unexpected result:", fmt.Sprintf("%q", str), ppres, err)
+			return
+		}
+	}
+}
+
+func TestEscapeString(t *testing.T) {
+	testdata := []string{"", "foo", `say "hi"`, `C:\temp`, "a\tb\nc\r", "\x00\x01\b\f\x1f\x7f", "äö €😀"}
+	expected := []string{`""`, `"foo"`, `"say \"hi\""`, `"C:\\temp"`, `"a\tb\nc\r"`,
+		`"\u0000\u0001\b\f\u001f\u007f"`, `"äö €😀"`}
+
+	for i, str := range testdata {
+		res := EscapeString(str)
+
+		if res != expected[i] {
+			t.Error("Unexpected result:", res, "expected:", expected[i])
+			return
+		}
+
+		// The escaped string is lexed into the original value
+
+		if tokens := LexToList("mytest", res); len(tokens) != 2 || tokens[0].ID != TokenStringValue ||
+			tokens[0].Val != str {
+			t.Error("Unexpected result:", tokens)
+			return
+		}
+	}
+
+	// The pretty printer uses the same escaping
+
+	ast, err := Parse("mytest", `{ foo(a: "C:\\temp\t\"x\"\u0001") }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := PrettyPrint(ast); err != nil || res != `{
+  foo(a: "C:\\temp\t\"x\"\u0001")
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestPrettyPrintCanonicalFloats(t *testing.T) {
+
+	ast, err := Parse("mytest", `{ foo(a: 1.5E10, b: .5, c: 3e-5, d: 12, e: "1.5", f: 2.0, g: -4) }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res, err := PrettyPrintWithOptions(ast, PrettyPrintOptions{CanonicalFloats: true}); err != nil || res != `{
+  foo(a: 1.5e+10, b: 5e-1, c: 3e-5, d: 12, e: "1.5", f: 2e+0, g: -4)
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := PrettyPrint(ast); err != nil || res != `{
+  foo(a: 1.5e10, b: .5, c: 3e-5, d: 12, e: "1.5", f: 2.0, g: -4)
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Strings which look like numbers or enum values keep their type
+
+	ast, _ = Parse("mytest", `{ f(a: "-4", b: "1.5", c: "NaN", d: "true", e: -4, f: true) }`)
+
+	res, err := PrettyPrint(ast)
+	if err != nil || res != `{
+  f(a: "-4", b: "1.5", c: "NaN", d: "true", e: -4, f: true)
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ast2, _ := Parse("mytest", res)
+
+	for i, arg := range ast2.FindAll(NodeArgument) {
+		if arg.Children[1].Token.ID != ast.FindAll(NodeArgument)[i].Children[1].Token.ID {
+			t.Error("Unexpected value type:", arg)
+			return
+		}
+	}
+}
+
+func TestPrettyPrintCompact(t *testing.T) {
+
+	input := `query q ($a: String="""multi
+line""") @dir(x: 1) {
+  user(id: 1, filter: {name: "a  b", tags: [1, 2]}) {
+    ...userFields
+    ... on Admin @include(if: true) {
+      text(value: """x
+  y""")
+    }
+  }
+}
+
+fragment userFields on User {
+  id
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrintCompact(ast)
+	if err != nil || res != `query q ($a: String="multi\nline") @dir(x: 1) { `+
+		`user(id: 1, filter: {name : "a  b", tags : [1, 2]}) { ...userFields ... on Admin @include(if: true) { `+
+		`text(value: "x\n  y") } } } fragment userFields on User { id }` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Wrapping options are ignored
+
+	if res2, err := PrettyPrintWithOptions(ast, PrettyPrintOptions{Compact: true, Expanded: true,
+		ArgumentWrapWidth: 10}); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+
+	ast2, err := Parse("mytest", res)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+}
+
+func TestPrettyPrintArgumentWrapping(t *testing.T) {
+
+	ast, err := Parse("mytest", `{ a: user(id: 1, name: "foo", filter: {score: 1.5}, first: 10) { `+
+		`friends(first: 10, after: "abcdefghijkl", order: ASC) @include(if: true) { id } other(id: 1) } `+
+		`search(text: "a long search text", limit: 100) }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrintWithOptions(ast, PrettyPrintOptions{ArgumentWrapWidth: 40})
+	if err != nil || res != `{
+  a : user(
+    id: 1,
+    name: "foo",
+    filter: {score : 1.5},
+    first: 10
+  ) {
+    friends(
+      first: 10,
+      after: "abcdefghijkl",
+      order: ASC
+    ) @include(if: true) {
+      id
+    }
+    other(id: 1)
+  }
+  search(
+    text: "a long search text",
+    limit: 100
+  )
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The wrapped output must parse into the same AST
+
+	ast2, err := Parse("mytest", res)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+
+	if res2, err := PrettyPrintWithOptions(ast2, PrettyPrintOptions{ArgumentWrapWidth: 40}); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+
+	if res, err := PrettyPrintWithOptions(ast, PrettyPrintOptions{ArgumentWrapWidth: 200}); err != nil || res != `{
+  a : user(id: 1, name: "foo", filter: {score : 1.5}, first: 10) {
+    friends(first: 10, after: "abcdefghijkl", order: ASC) @include(if: true) {
+      id
+    }
+    other(id: 1)
+  }
+  search(text: "a long search text", limit: 100)
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestPrettyPrintExpanded(t *testing.T) {
+
+	input := `{
+  user(id: 1, filter: {name: "foo", tags: ["a", "b"], range: {from: 1, to: 10}, empty: {}}, list: [{a: 1}, {b: 2}]) @cached(ttl: 10) {
+    name
+    friends { id }
+  }
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrintExpanded(ast)
+	if err != nil || res != `
+{
+  user(
+    id: 1,
+    filter: {
+      name : "foo",
+      tags : ["a", "b"],
+      range : {
+        from : 1,
+        to : 10
+      },
+      empty : {}
+    },
+    list: [{
+      a : 1
+    }, {
+      b : 2
+    }]
+  ) @cached(
+    ttl: 10
+  ) {
+    name
+    friends {
+      id
+    }
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The expanded output parses into the same AST
+
+	ast2, err := Parse("mytest", res)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+
+	if res2, err := PrettyPrintExpanded(ast2); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+}
+
+func TestFormat(t *testing.T) {
+
+	res, err := Format("mytest", `query   q($id:Int=1){user(id:$id,
+        name:"foo"){...userFields,
+  friends { id
+name }}}
+
+fragment userFields on User{ id    }`)
+
+	if err != nil || res != `
+query q ($id: Int=1) {
+  user(id: $id, name: "foo") {
+    ...userFields
+    friends {
+      id
+      name
+    }
+  }
+}
+
+fragment userFields on User {
+  id
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Formatting is idempotent
+
+	if res2, err := Format("mytest", res); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+
+	// Multi-line values in indented positions are written as normal strings
+
+	res, err = Format("mytest", "{ f(a: \"a\\nb\", b: \"\"\"c\n  d\"\"\") { g(a: \"x\\n  y\") } }")
+	if err != nil || res != `
+{
+  f(a: "a\nb", b: "c\n  d") {
+    g(a: "x\n  y")
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res2, err := Format("mytest", res); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+
+	if _, err := Format("mytest", "query {\n\tuser(id: 1 {\n\t\tname\n\t}\n}"); err == nil || err.Error() != `
+Parse error in mytest: Name expected ({) (Line:2 Pos:14)
+	user(id: 1 {
+	           ^`[1:] {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if _, err := Format("mytest", "{ user(id: ) }"); err == nil || err.Error() != `
+Parse error in mytest: Term cannot start an expression (}) (Line:1 Pos:14)
+{ user(id: ) }
+             ^`[1:] {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
 func TestErrorCases(t *testing.T) {
 
 	astres, _ := ParseWithRuntime("mytest", `{ a }`, &TestRuntimeProvider{})
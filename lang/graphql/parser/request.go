@@ -0,0 +1,57 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"encoding/json"
+)
+
+/*
+ToRequestBody produces the JSON body of a GraphQL HTTP request for a given
+AST (either a document or a single operation definition) and a map of
+variables. The query is pretty printed in compact form. The operation name is
+only included if the operation has a name.
+*/
+func ToRequestBody(op *ASTNode, vars map[string]interface{}) ([]byte, error) {
+
+	query, err := PrettyPrintCompact(op)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Query         string                 `json:"query"`
+		Variables     map[string]interface{} `json:"variables,omitempty"`
+		OperationName string                 `json:"operationName,omitempty"`
+	}{query, vars, operationName(op)})
+}
+
+/*
+operationName returns the name of the first operation in a given AST or an
+empty string if the operation is anonymous.
+*/
+func operationName(n *ASTNode) string {
+
+	if n.Name == NodeDocument {
+		for _, ed := range n.Children {
+			if len(ed.Children) > 0 && ed.Children[0].Name == NodeOperationDefinition {
+				return operationName(ed.Children[0])
+			}
+		}
+	}
+
+	for _, child := range n.Children {
+		if child.Name == NodeName {
+			return child.Token.Val
+		}
+	}
+
+	return ""
+}
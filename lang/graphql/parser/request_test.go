@@ -0,0 +1,57 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestToRequestBody(t *testing.T) {
+
+	ast, err := Parse("mytest", `query getUser($id: Int) { user(id: $id) { name } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := ToRequestBody(ast, map[string]interface{}{"id": 4})
+	if err != nil || string(res) != `{"query":"query getUser ($id: Int) { user(id: $id) { name } }",`+
+		`"variables":{"id":4},"operationName":"getUser"}` {
+		t.Error("Unexpected result:", string(res), err)
+		return
+	}
+
+	// Operation definitions can be used directly
+
+	res, err = ToRequestBody(ast.Children[0].Children[0], nil)
+	if err != nil || string(res) != `{"query":"query getUser ($id: Int) { user(id: $id) { name } }",`+
+		`"operationName":"getUser"}` {
+		t.Error("Unexpected result:", string(res), err)
+		return
+	}
+
+	ast, err = Parse("mytest", `{ user { name } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err = ToRequestBody(ast, nil)
+	if err != nil || string(res) != `{"query":"{ user { name } }"}` {
+		t.Error("Unexpected result:", string(res), err)
+		return
+	}
+
+	if _, err = ToRequestBody(&ASTNode{Name: "foo", Token: &LexToken{}}, nil); err == nil ||
+		err.Error() != "Could not find template for foo (tempkey: foo)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
@@ -0,0 +1,898 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krotik/common/stringutil"
+)
+
+/*
+RemoveRedundantAliases removes all aliases from a given AST which are equal
+to the name of their field (e.g. name: name). The AST is modified in place.
+*/
+func RemoveRedundantAliases(doc *ASTNode) *ASTNode {
+
+	if doc.Name == NodeField && len(doc.Children) > 1 &&
+		doc.Children[0].Name == NodeAlias && doc.Children[1].Name == NodeName &&
+		doc.Children[0].Token.Val == doc.Children[1].Token.Val {
+
+		doc.Children = doc.Children[1:]
+	}
+
+	for _, child := range doc.Children {
+		RemoveRedundantAliases(child)
+	}
+
+	return doc
+}
+
+/*
+SortDirectives sorts the directives of all nodes of a given AST alphabetically
+by name. If pinBuiltIn is set then the built-in directives @include and @skip
+are placed before all other directives. The AST is modified in place.
+*/
+func SortDirectives(doc *ASTNode, pinBuiltIn bool) *ASTNode {
+
+	if doc.Name == NodeDirectives {
+
+		sort.SliceStable(doc.Children, func(i, j int) bool {
+			a, b := doc.Children[i].Children[0].Token.Val, doc.Children[j].Children[0].Token.Val
+
+			if pinBuiltIn {
+				aBuiltIn, bBuiltIn := a == "include" || a == "skip", b == "include" || b == "skip"
+
+				if aBuiltIn != bBuiltIn {
+					return aBuiltIn
+				}
+			}
+
+			return a < b
+		})
+	}
+
+	for _, child := range doc.Children {
+		SortDirectives(child, pinBuiltIn)
+	}
+
+	return doc
+}
+
+/*
+CanonicalizeArguments sorts the arguments of all fields and directives and the
+fields of all object values of a given AST alphabetically by name. The order
+of selections and of list values is not changed. Queries which only differ in
+the order of their arguments have the same canonical form (e.g. for stable
+cache keys). The AST is modified in place.
+*/
+func CanonicalizeArguments(doc *ASTNode) *ASTNode {
+
+	if doc.Name == NodeArguments {
+
+		sort.SliceStable(doc.Children, func(i, j int) bool {
+			return doc.Children[i].Children[0].Token.Val < doc.Children[j].Children[0].Token.Val
+		})
+
+	} else if doc.Name == NodeObjectValue {
+
+		sort.SliceStable(doc.Children, func(i, j int) bool {
+			return doc.Children[i].Token.Val < doc.Children[j].Token.Val
+		})
+	}
+
+	for _, child := range doc.Children {
+		CanonicalizeArguments(child)
+	}
+
+	return doc
+}
+
+/*
+ShapeFingerprint produces a pretty printed string of a given AST where all
+scalar argument values are replaced by a ? placeholder. Queries which only
+differ in their argument values have the same fingerprint. Variables are not
+replaced. The given AST is not modified. If the AST cannot be pretty printed
+(e.g. a manually constructed AST with unknown nodes) the fingerprint is the
+string representation of the AST.
+*/
+func ShapeFingerprint(op *ASTNode) string {
+	var replaceValues func(n *ASTNode, inArgs bool)
+
+	replaceValues = func(n *ASTNode, inArgs bool) {
+
+		if inArgs && (n.Name == NodeValue || n.Name == NodeEnumValue) {
+
+			// Enum values are printed without quotes
+
+			n.Name = NodeEnumValue
+			n.Token.Val = "?"
+		}
+
+		for _, child := range n.Children {
+			replaceValues(child, inArgs || n.Name == NodeArguments)
+		}
+	}
+
+	ast := op.Clone()
+	replaceValues(ast, false)
+
+	res, err := PrettyPrint(ast)
+	if err != nil {
+		return ast.String()
+	}
+
+	return res
+}
+
+/*
+FlattenInlineFragments merges the selections of all inline fragments with a
+type condition matching a given type name into their parent selection set.
+Inline fragments with a non-matching type condition or which are excluded by
+a @skip(if: true) or @include(if: false) directive are removed. Other
+directives of a merged inline fragment are added to all merged selections.
+Only selection sets which select from the given type are flattened (i.e.
+selection sets of sub fields are not touched). The AST is modified in place.
+*/
+func FlattenInlineFragments(doc *ASTNode, typename string) *ASTNode {
+
+	if doc.Name == NodeSelectionSet {
+		flattenSelectionSet(doc, typename)
+		return doc
+	}
+
+	for _, child := range doc.Children {
+
+		if doc.Name != NodeField || child.Name == NodeSelectionSet {
+			FlattenInlineFragments(child, typename)
+		}
+	}
+
+	return doc
+}
+
+/*
+flattenSelectionSet merges matching inline fragments into a given selection set.
+*/
+func flattenSelectionSet(selectionSet *ASTNode, typename string) {
+	var children []*ASTNode
+
+	for _, child := range selectionSet.Children {
+		var typeCondition, directives, selections *ASTNode
+
+		if child.Name != NodeInlineFragment {
+			children = append(children, child)
+			continue
+		}
+
+		for _, c := range child.Children {
+			switch c.Name {
+			case NodeTypeCondition:
+				typeCondition = c
+			case NodeDirectives:
+				directives = c
+			case NodeSelectionSet:
+				selections = c
+			}
+		}
+
+		if typeCondition != nil && typeCondition.Token.Val != typename {
+			continue
+		}
+
+		if v, ok := child.DirectiveArg("skip", "if"); ok && v == "true" {
+			continue
+		}
+
+		if v, ok := child.DirectiveArg("include", "if"); ok && v == "false" {
+			continue
+		}
+
+		flattenSelectionSet(selections, typename)
+
+		for _, selection := range selections.Children {
+			if directives != nil {
+				addDirectives(selection, directives)
+			}
+			children = append(children, selection)
+		}
+	}
+
+	selectionSet.Children = children
+
+	for _, child := range children {
+		child.Parent = selectionSet
+	}
+}
+
+/*
+addDirectives adds copies of the given directives to a field or fragment spread.
+*/
+func addDirectives(node *ASTNode, directives *ASTNode) {
+
+	for _, child := range node.Children {
+		if child.Name == NodeDirectives {
+			for _, directive := range directives.Children {
+				d := directive.Clone()
+				d.Parent = child
+				child.Children = append(child.Children, d)
+			}
+			return
+		}
+	}
+
+	// Directives must be inserted before a selection set
+
+	pos := len(node.Children)
+	if pos > 0 && node.Children[pos-1].Name == NodeSelectionSet {
+		pos--
+	}
+
+	node.Children = append(node.Children, nil)
+	copy(node.Children[pos+1:], node.Children[pos:])
+	node.Children[pos] = directives.Clone()
+	node.Children[pos].Parent = node
+}
+
+/*
+FragmentMap returns all fragment definitions of a given document by their
+name. If a name is defined more than once the last definition is returned.
+*/
+func FragmentMap(doc *ASTNode) map[string]*ASTNode {
+	fragments, _ := fragmentMap(doc)
+	return fragments
+}
+
+/*
+FragmentMapStrict returns all fragment definitions of a given document by
+their name. Returns an ErrDuplicateDefinition error if a name is defined more
+than once.
+*/
+func FragmentMapStrict(doc *ASTNode) (map[string]*ASTNode, error) {
+	fragments, err := fragmentMap(doc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fragments, nil
+}
+
+/*
+fragmentMap collects all fragment definitions of a given document. The
+returned error reports the first duplicate definition.
+*/
+func fragmentMap(doc *ASTNode) (map[string]*ASTNode, error) {
+	var err error
+
+	fragments := make(map[string]*ASTNode)
+
+	for _, ed := range doc.Children {
+		if len(ed.Children) > 0 && ed.Children[0].Name == NodeFragmentDefinition {
+			fd := ed.Children[0]
+			name := fd.Children[0].Token.Val
+
+			if _, ok := fragments[name]; ok && err == nil {
+				err = newASTError(ErrDuplicateDefinition, "fragment "+name, fd)
+			}
+
+			fragments[name] = fd
+		}
+	}
+
+	return fragments, err
+}
+
+/*
+InlineFragments replaces all fragment spreads in a given AST with the
+selections of the referenced fragment definitions. Directives of a fragment
+spread are added to all inlined selections. The fragment definitions are
+removed from the resulting AST. Returns an error if a fragment is unknown or
+if fragments reference each other in a cycle. The given AST is not modified.
+*/
+func InlineFragments(doc *ASTNode) (*ASTNode, error) {
+	var children []*ASTNode
+	var err error
+
+	doc = doc.Clone()
+	fragments := FragmentMap(doc)
+
+	for _, ed := range doc.Children {
+		var stack []string
+
+		if len(ed.Children) > 0 && ed.Children[0].Name == NodeFragmentDefinition {
+
+			// Fragment definitions are expanded as well to detect cycles
+			// in unused fragments
+
+			stack = []string{ed.Children[0].Children[0].Token.Val}
+
+		} else {
+
+			children = append(children, ed)
+		}
+
+		if err = inlineFragmentSpreads(ed, fragments, stack); err != nil {
+			return nil, err
+		}
+	}
+
+	doc.Children = children
+
+	SetParents(doc)
+
+	return doc, nil
+}
+
+/*
+inlineFragmentSpreads replaces all fragment spreads in the selection sets of
+a given AST. The stack contains the names of all fragments which are currently
+being expanded.
+*/
+func inlineFragmentSpreads(node *ASTNode, fragments map[string]*ASTNode, stack []string) error {
+
+	if node.Name != NodeSelectionSet {
+
+		for _, child := range node.Children {
+			if err := inlineFragmentSpreads(child, fragments, stack); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	var children []*ASTNode
+
+	for _, child := range node.Children {
+
+		if child.Name != NodeFragmentSpread {
+
+			if err := inlineFragmentSpreads(child, fragments, stack); err != nil {
+				return err
+			}
+
+			children = append(children, child)
+			continue
+		}
+
+		name := child.Token.Val
+
+		if stringutil.IndexOf(name, stack) != -1 {
+			return newASTError(ErrCyclicFragment, strings.Join(append(stack, name), " -> "), child)
+		}
+
+		fd, ok := fragments[name]
+		if !ok {
+			return newASTError(ErrUnknownFragment, name, child)
+		}
+
+		selections := fd.Children[len(fd.Children)-1].Clone()
+
+		if err := inlineFragmentSpreads(selections, fragments, append(stack[:len(stack):len(stack)], name)); err != nil {
+			return err
+		}
+
+		for _, selection := range selections.Children {
+			for _, c := range child.Children {
+				if c.Name == NodeDirectives {
+					addDirectives(selection, c)
+				}
+			}
+
+			children = append(children, selection)
+		}
+	}
+
+	node.Children = children
+
+	return nil
+}
+
+/*
+RedactedValue is the value which replaces the values of sensitive arguments.
+*/
+const RedactedValue = "[REDACTED]"
+
+/*
+Redact returns a copy of a given AST where the values of all arguments and
+input object fields with a sensitive name (compared case-insensitive) are
+replaced by a "[REDACTED]" string value. Variables are not replaced since
+their values are not part of the AST - use SensitiveVariables to find the
+variables which should be removed from the variable values. The given AST is
+not modified.
+*/
+func Redact(doc *ASTNode, sensitiveArgs []string) *ASTNode {
+	var redact func(n *ASTNode)
+
+	redact = func(n *ASTNode) {
+
+		if value := sensitiveValue(n, sensitiveArgs); value != nil && value.Name != NodeVariable {
+			*value = ASTNode{Name: NodeValue, Token: &LexToken{TokenStringValue, value.Token.Pos,
+				RedactedValue, value.Token.Lline, value.Token.Lpos}, Parent: value.Parent}
+		}
+
+		for _, child := range n.Children {
+			redact(child)
+		}
+	}
+
+	ast := doc.Clone()
+	redact(ast)
+
+	return ast
+}
+
+/*
+SensitiveVariables returns the names of all variables of a given AST which
+are passed to arguments or input object fields with a sensitive name.
+*/
+func SensitiveVariables(doc *ASTNode, sensitiveArgs []string) []string {
+	var ret []string
+	var visit func(n *ASTNode)
+
+	visit = func(n *ASTNode) {
+
+		if value := sensitiveValue(n, sensitiveArgs); value != nil {
+			for _, v := range append([]*ASTNode{value}, value.FindAll(NodeVariable)...) {
+				if v.Name == NodeVariable && stringutil.IndexOf(v.Token.Val, ret) == -1 {
+					ret = append(ret, v.Token.Val)
+				}
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+
+	visit(doc)
+
+	return ret
+}
+
+/*
+sensitiveValue returns the value node of a given argument or object field
+node if it has a sensitive name. Returns nil otherwise.
+*/
+func sensitiveValue(n *ASTNode, sensitiveArgs []string) *ASTNode {
+	var name string
+	var value *ASTNode
+
+	if n.Name == NodeArgument && len(n.Children) > 1 {
+		name, value = n.Children[0].Token.Val, n.Children[1]
+	} else if n.Name == NodeObjectField && len(n.Children) > 0 {
+		name, value = n.Token.Val, n.Children[0]
+	}
+
+	for _, s := range sensitiveArgs {
+		if value != nil && strings.EqualFold(name, s) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+/*
+Limit returns a copy of a given AST where each selection set contains at most
+a given number of fields. Fragment spreads are inlined first so the fields of
+fragments count toward the limit. The fields of inline fragments count toward
+the limit of their enclosing selection set. If fragments cannot be inlined
+(e.g. because of an unknown fragment) each fragment spread counts as one
+field. The given AST is not modified.
+*/
+func Limit(doc *ASTNode, maxFieldsPerLevel int) *ASTNode {
+	var visit func(n *ASTNode)
+
+	ast, err := InlineFragments(doc)
+	if err != nil {
+		ast = doc.Clone()
+	}
+
+	visit = func(n *ASTNode) {
+
+		if n.Name == NodeSelectionSet {
+			var count int
+			limitSelectionSet(n, maxFieldsPerLevel, &count, visit)
+			return
+		}
+
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+
+	visit(ast)
+
+	return ast
+}
+
+/*
+limitSelectionSet removes all selections from a given selection set after a
+given number of fields were counted. The visit function is called for all
+remaining fields.
+*/
+func limitSelectionSet(selectionSet *ASTNode, max int, count *int, visit func(n *ASTNode)) {
+	var children []*ASTNode
+
+	for _, child := range selectionSet.Children {
+
+		if child.Name == NodeInlineFragment {
+
+			limitSelectionSet(child.Children[len(child.Children)-1], max, count, visit)
+
+			if len(child.Children[len(child.Children)-1].Children) > 0 {
+				children = append(children, child)
+			}
+
+		} else if *count < max {
+
+			*count++
+			children = append(children, child)
+			visit(child)
+		}
+	}
+
+	selectionSet.Children = children
+}
+
+/*
+MergeDocuments merges the definitions of the given documents into a single
+document. Returns an ErrDuplicateDefinition error if operations or fragments
+with the same name are defined more than once or if more than one anonymous
+operation is defined. The given documents are not modified.
+*/
+func MergeDocuments(docs ...*ASTNode) (*ASTNode, error) {
+	var anonymous bool
+
+	operations := make(map[string]bool)
+	fragments := make(map[string]bool)
+
+	ret := &ASTNode{Name: NodeDocument, Token: &LexToken{TokenGeneral, 0, "", 0, 0},
+		Children: make([]*ASTNode, 0)}
+
+	for _, doc := range docs {
+		for _, ed := range doc.Children {
+
+			if len(ed.Children) > 0 {
+				def := ed.Children[0]
+
+				if def.Name == NodeFragmentDefinition {
+					name := def.Children[0].Token.Val
+
+					if fragments[name] {
+						return nil, newASTError(ErrDuplicateDefinition, "fragment "+name, def)
+					}
+
+					fragments[name] = true
+
+				} else if name := operationName(def); name != "" {
+
+					if operations[name] {
+						return nil, newASTError(ErrDuplicateDefinition, "operation "+name, def)
+					}
+
+					operations[name] = true
+
+				} else {
+
+					if anonymous {
+						return nil, newASTError(ErrDuplicateDefinition, "anonymous operation", def)
+					}
+
+					anonymous = true
+				}
+			}
+
+			ret.Children = append(ret.Children, ed.Clone())
+		}
+	}
+
+	SetParents(ret)
+
+	return ret, nil
+}
+
+/*
+AnnotateCost adds a @cost(value: N) directive to all fields of a given AST
+whose name is in a given map of costs. Fields which already have a @cost
+directive are not changed. The AST is modified in place.
+*/
+func AnnotateCost(doc *ASTNode, costs map[string]int) *ASTNode {
+
+	if doc.Name == NodeField {
+		var name string
+
+		for _, child := range doc.Children {
+			if child.Name == NodeName {
+				name = child.Token.Val
+			}
+		}
+
+		if cost, ok := costs[name]; ok {
+			if _, ok := doc.Directive("cost"); !ok {
+				addDirectives(doc, newNode(NodeDirectives, TokenGeneral, "",
+					newNode(NodeDirective, TokenGeneral, "",
+						newNode(NodeName, TokenName, "cost"),
+						newNode(NodeArguments, TokenGeneral, "",
+							newNode(NodeArgument, TokenGeneral, "",
+								newNode(NodeName, TokenName, "value"),
+								newNode(NodeValue, TokenIntValue, fmt.Sprint(cost)))))))
+			}
+		}
+	}
+
+	for _, child := range doc.Children {
+		AnnotateCost(child, costs)
+	}
+
+	return doc
+}
+
+/*
+newNode creates a new ASTNode which is not connected to any input.
+*/
+func newNode(name string, id LexTokenID, val string, children ...*ASTNode) *ASTNode {
+	ret := &ASTNode{Name: name, Token: &LexToken{id, 0, val, 0, 0}, Children: make([]*ASTNode, 0, len(children))}
+
+	for _, child := range children {
+		child.Parent = ret
+		ret.Children = append(ret.Children, child)
+	}
+
+	return ret
+}
+
+/*
+SetArgument adds an argument with a given name and value to a given field
+node. The value of an existing argument with the same name is replaced. The
+value must be a value node (e.g. created by ParseValue).
+*/
+func SetArgument(field *ASTNode, name string, value *ASTNode) error {
+	var args *ASTNode
+
+	if field.Name != NodeField {
+		return fmt.Errorf("Cannot set argument on %v node", field.Name)
+	} else if value == nil {
+		return fmt.Errorf("Cannot set argument %v without a value", name)
+	}
+
+	for _, child := range field.Children {
+		if child.Name == NodeArguments {
+			args = child
+		}
+	}
+
+	if args == nil {
+
+		// Arguments must be inserted after the field name
+
+		pos := 0
+		for pos < len(field.Children) && field.Children[pos].Name != NodeName {
+			pos++
+		}
+		pos++
+
+		args = newNode(NodeArguments, TokenGeneral, "")
+		args.Parent = field
+
+		field.Children = append(field.Children, nil)
+		copy(field.Children[pos+1:], field.Children[pos:])
+		field.Children[pos] = args
+	}
+
+	for _, arg := range args.Children {
+		if arg.Children[0].Token.Val == name {
+			arg.Children[1] = value
+			value.Parent = arg
+			return nil
+		}
+	}
+
+	arg := newNode(NodeArgument, TokenGeneral, "", newNode(NodeName, TokenName, name), value)
+	arg.Parent = args
+	args.Children = append(args.Children, arg)
+
+	return nil
+}
+
+/*
+RemoveArgument removes the argument with a given name from a given field node.
+The arguments of the field are removed completely if the last argument was
+removed. Returns true if an argument was removed.
+*/
+func RemoveArgument(field *ASTNode, name string) bool {
+
+	for i, child := range field.Children {
+
+		if child.Name != NodeArguments {
+			continue
+		}
+
+		for j, arg := range child.Children {
+
+			if arg.Children[0].Token.Val == name {
+
+				child.Children = append(child.Children[:j], child.Children[j+1:]...)
+
+				if len(child.Children) == 0 {
+					field.Children = append(field.Children[:i], field.Children[i+1:]...)
+				}
+
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+ReplaceArgumentValue replaces the value of the argument with a given name on
+all fields of a given document which have a given path. Like the paths of
+EnforceFieldAllowlist the path consists of the names (not the aliases) of the
+fields separated by dots (e.g. user.friends). Paths of fields in fragment
+definitions start at the fragment. Each replaced argument receives its own
+copy of the new value. Returns the number of replaced values (0 if the new
+value is nil).
+*/
+func ReplaceArgumentValue(doc *ASTNode, fieldPath, argName string, newValue *ASTNode) int {
+	var visit func(n *ASTNode, path string)
+	var count int
+
+	if newValue == nil {
+		return 0
+	}
+
+	visit = func(n *ASTNode, path string) {
+
+		if n.Name == NodeField {
+
+			if path != "" {
+				path += "."
+			}
+
+			for _, child := range n.Children {
+				if child.Name == NodeName {
+					path += child.Token.Val
+				}
+			}
+
+			if path == fieldPath {
+				for _, child := range n.Children {
+
+					if child.Name != NodeArguments {
+						continue
+					}
+
+					for _, arg := range child.Children {
+						if arg.Children[0].Token.Val == argName {
+							arg.Children[1] = newValue.Clone()
+							arg.Children[1].Parent = arg
+							count++
+						}
+					}
+				}
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child, path)
+		}
+	}
+
+	visit(doc, "")
+
+	return count
+}
+
+/*
+RenameField changes the name of a given field node. The alias, arguments,
+directives and selection set of the field are kept.
+*/
+func RenameField(field *ASTNode, newName string) error {
+
+	if field.Name != NodeField {
+		return fmt.Errorf("Cannot rename %v node", field.Name)
+	} else if !IsValidName(newName) {
+		return fmt.Errorf("Invalid field name: %v", newName)
+	}
+
+	for _, child := range field.Children {
+		if child.Name == NodeName {
+			child.Token.Val = newName
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Field has no name")
+}
+
+/*
+SplitOperations returns a document for each operation of a given document.
+Each document contains a copy of the operation and copies of all fragment
+definitions which are used by the operation. Returns an error if a fragment
+is unknown or if fragments reference each other in a cycle. The given
+document is not modified.
+*/
+func SplitOperations(doc *ASTNode) ([]*ASTNode, error) {
+	var ret []*ASTNode
+
+	fragments := FragmentMap(doc)
+
+	for _, ed := range doc.Children {
+
+		if len(ed.Children) == 0 || ed.Children[0].Name != NodeOperationDefinition {
+			continue
+		}
+
+		used := make(map[string]bool)
+
+		if err := usedFragments(ed, fragments, used, nil); err != nil {
+			return nil, err
+		}
+
+		opDoc := newNode(NodeDocument, TokenGeneral, "", ed.Clone())
+
+		for _, fed := range doc.Children {
+			if len(fed.Children) > 0 && fed.Children[0].Name == NodeFragmentDefinition &&
+				used[fed.Children[0].Children[0].Token.Val] {
+
+				opDoc.Children = append(opDoc.Children, fed.Clone())
+			}
+		}
+
+		SetParents(opDoc)
+
+		ret = append(ret, opDoc)
+	}
+
+	return ret, nil
+}
+
+/*
+usedFragments collects the names of all fragments which are used by a given
+AST. The stack contains the names of all fragments which are currently being
+visited.
+*/
+func usedFragments(node *ASTNode, fragments map[string]*ASTNode, used map[string]bool, stack []string) error {
+
+	if node.Name == NodeFragmentSpread {
+		name := node.Token.Val
+
+		if stringutil.IndexOf(name, stack) != -1 {
+			return newASTError(ErrCyclicFragment, strings.Join(append(stack, name), " -> "), node)
+		}
+
+		fd, ok := fragments[name]
+		if !ok {
+			return newASTError(ErrUnknownFragment, name, node)
+		}
+
+		if !used[name] {
+			used[name] = true
+
+			if err := usedFragments(fd, fragments, used, append(stack[:len(stack):len(stack)], name)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, child := range node.Children {
+		if err := usedFragments(child, fragments, used, stack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
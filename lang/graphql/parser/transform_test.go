@@ -0,0 +1,1019 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRemoveRedundantAliases(t *testing.T) {
+
+	input := `{
+  name : name
+  me : user(id: 1) {
+    id : id
+    pic : profilePic
+  }
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(RemoveRedundantAliases(ast))
+	if err != nil || res != `{
+  name
+  me : user(id: 1) {
+    id
+    pic : profilePic
+  }
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestShapeFingerprint(t *testing.T) {
+
+	fingerprint := func(input string) string {
+		ast, err := Parse("mytest", input)
+		if err != nil {
+			return err.Error()
+		}
+
+		return ShapeFingerprint(ast)
+	}
+
+	fp1 := fingerprint(`{ user(id:1) { name @include(if: true) } }`)
+	fp2 := fingerprint(`{ user(id:2) { name @include(if: false) } }`)
+
+	if fp1 != fp2 || fp1 != `{
+  user(id: ?) {
+    name @include(if: ?)
+  }
+}` {
+		t.Error("Unexpected result:", fp1, fp2)
+		return
+	}
+
+	input := `query q ($id: Int=1) { user(id:$id, role:ADMIN, filter: {name:"x", tags:[1, 2]}) { name } }`
+
+	if res := fingerprint(input); res != `query q ($id: Int=1) {
+  user(id: $id, role: ?, filter: {name : ?, tags : [?, ?]}) {
+    name
+  }
+}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Make sure the original AST is unchanged
+
+	ast, _ := Parse("mytest", input)
+	ShapeFingerprint(ast)
+
+	if res, _ := PrettyPrint(ast); res != `query q ($id: Int=1) {
+  user(id: $id, role: ADMIN, filter: {name : "x", tags : [1, 2]}) {
+    name
+  }
+}` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fingerprint(`{ user(id:2) { name } }`); res == fp1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// ASTs which cannot be pretty printed produce the string representation
+
+	unknown := newNode("Unknown", TokenGeneral, "", newNode(NodeValue, TokenIntValue, "1"))
+
+	if _, err := PrettyPrint(unknown); err == nil {
+		t.Error("Pretty printing should fail")
+		return
+	}
+
+	if res := ShapeFingerprint(unknown); res != unknown.String() {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestFlattenInlineFragments(t *testing.T) {
+
+	input := `{
+  id
+  ... on User {
+    name
+    friends {
+      ... on User {
+        name
+      }
+    }
+  }
+  ... on Group {
+    members
+  }
+  ... {
+    kind
+    ... on User {
+      age
+    }
+  }
+  ... on User @include(if: $details) {
+    profile {
+      pic
+    }
+    email @foo(x: 1)
+    ...userFields
+  }
+  ... on User @skip(if: true) {
+    secret
+  }
+  ... on User @include(if: false) {
+    secret2
+  }
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(FlattenInlineFragments(ast, "User"))
+	if err != nil || res != `{
+  id
+  name
+  friends {
+    ... on User {
+      name
+    }
+  }
+  kind
+  age
+  profile @include(if: $details) {
+    pic
+  }
+  email @foo(x: 1) @include(if: $details)
+  ...userFields @include(if: $details)
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ ... on Group { members } }`)
+
+	if res, err := PrettyPrint(FlattenInlineFragments(ast, "User")); err != nil || res != `{
+}` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestInlineFragments(t *testing.T) {
+
+	input := `
+query withNestedFragments {
+  user(id: 4) {
+    friends(first: 10) {
+      ...friendFields
+    }
+    ... on User {
+      ...standardProfilePic @include(if: $pic)
+    }
+  }
+}
+
+fragment friendFields on User {
+  id
+  name
+  ...standardProfilePic
+}
+
+fragment standardProfilePic on User {
+  profilePic(size: 50)
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := InlineFragments(ast)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if pp, err := PrettyPrint(res); err != nil || pp != `query withNestedFragments {
+  user(id: 4) {
+    friends(first: 10) {
+      id
+      name
+      profilePic(size: 50)
+    }
+    ... on User {
+      profilePic(size: 50) @include(if: $pic)
+    }
+  }
+}` {
+		t.Error("Unexpected result:", pp, err)
+		return
+	}
+
+	// The original AST is not modified
+
+	if len(ast.Children) != 3 {
+		t.Error("Unexpected result:", ast)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ ...a }
+fragment a on User { id ...b }
+fragment b on User { name ...a }`)
+
+	if _, err := InlineFragments(ast); err == nil || err.Error() !=
+		"Parse error in AST: Cyclic fragment reference (a -> b -> a) (Line:3 Pos:31)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ id }
+fragment a on User { id ...a }`)
+
+	if _, err := InlineFragments(ast); err == nil || err.Error() !=
+		"Parse error in AST: Cyclic fragment reference (a -> a) (Line:2 Pos:29)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ ...a }`)
+
+	if _, err := InlineFragments(ast); err == nil || err.Error() !=
+		"Parse error in AST: Unknown fragment (a) (Line:1 Pos:6)" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestRedact(t *testing.T) {
+
+	ast, err := Parse("mytest", `mutation m($pw: String, $tokens: Tokens) {
+  login(user: "alice", Password: "secret", remember: true) { id }
+  update(input: {name: "Alice", password: "secret2", tokens: $tokens}, pin: [1, 2, 3]) { id }
+  other(password: $pw) { id }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	orig, _ := PrettyPrint(ast)
+
+	res, err := PrettyPrint(Redact(ast, []string{"password", "PIN", "tokens"}))
+	if err != nil || res != `
+mutation m ($pw: String, $tokens: Tokens) {
+  login(user: "alice", Password: "[REDACTED]", remember: true) {
+    id
+  }
+  update(input: {name : "Alice", password : "[REDACTED]", tokens : $tokens}, pin: "[REDACTED]") {
+    id
+  }
+  other(password: $pw) {
+    id
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The original AST is not modified
+
+	if res, _ := PrettyPrint(ast); res != orig {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SensitiveVariables(ast, []string{"password", "PIN", "tokens"}); fmt.Sprint(res) != "[tokens pw]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SensitiveVariables(ast, []string{"input"}); fmt.Sprint(res) != "[tokens]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestLimit(t *testing.T) {
+
+	ast, err := Parse("mytest", `query {
+  user {
+    id
+    name
+    email
+    friends {
+      id
+      name
+      age
+    }
+  }
+  posts { ...postFields }
+  stats
+}
+fragment postFields on Post { title ... on Article { body } author { name } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	orig, _ := PrettyPrint(ast)
+
+	res, err := PrettyPrint(Limit(ast, 2))
+	if err != nil || res != `
+query {
+  user {
+    id
+    name
+  }
+  posts {
+    title
+    ... on Article {
+      body
+    }
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	res, err = PrettyPrint(Limit(ast, 1))
+	if err != nil || res != `
+query {
+  user {
+    id
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The original AST is not modified
+
+	if res, _ := PrettyPrint(ast); res != orig {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Fragment spreads which cannot be inlined count as one field
+
+	ast, _ = Parse("mytest", `{ user { ...unknown id name } }`)
+
+	if res, err := PrettyPrint(Limit(ast, 2)); err != nil || res != `
+{
+  user {
+    ...unknown
+    id
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestSortDirectives(t *testing.T) {
+	input := `query q @b @a {
+  user @cached(ttl: 10) @skip(if: $noUser) @auth(role: ADMIN) {
+    name @upper @include(if: true) @deprecated
+  }
+}`
+
+	ast, err := Parse("mytest", input)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(SortDirectives(ast, false))
+	if err != nil || res != `
+query q @a @b {
+  user @auth(role: ADMIN) @cached(ttl: 10) @skip(if: $noUser) {
+    name @deprecated @include(if: true) @upper
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ast, _ = Parse("mytest", input)
+
+	res, err = PrettyPrint(SortDirectives(ast, true))
+	if err != nil || res != `
+query q @a @b {
+  user @skip(if: $noUser) @auth(role: ADMIN) @cached(ttl: 10) {
+    name @include(if: true) @deprecated @upper
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The sorted output parses into the same AST
+
+	ast2, err := Parse("mytest", res)
+	if err != nil || ast2.String() != ast.String() {
+		t.Error("Unexpected result:", ast2, err)
+		return
+	}
+}
+
+func TestCanonicalizeArguments(t *testing.T) {
+
+	ast1, err := Parse("mytest", `{
+  user(name: "foo", filter: {z: 1, a: [3, 1, 2], m: {y: true, b: false}}, id: 1) @auth(scope: "x", role: ADMIN) {
+    name
+    friends(last: 2, first: 10) { id }
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ast2, err := Parse("mytest", `{
+  user(id: 1, filter: {a: [3, 1, 2], m: {b: false, y: true}, z: 1}, name: "foo") @auth(role: ADMIN, scope: "x") {
+    name
+    friends(first: 10, last: 2) { id }
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res1, err := PrettyPrint(CanonicalizeArguments(ast1))
+	if err != nil || res1 != `
+{
+  user(filter: {a : [3, 1, 2], m : {b : false, y : true}, z : 1}, id: 1, name: "foo") @auth(role: ADMIN, scope: "x") {
+    name
+    friends(first: 10, last: 2) {
+      id
+    }
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res1, err)
+		return
+	}
+
+	if res2, err := PrettyPrint(CanonicalizeArguments(ast2)); err != nil || res2 != res1 {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+
+	// List order is significant
+
+	ast3, _ := Parse("mytest", `{ user(filter: {a: [1, 2, 3]}, id: 1) { name } }`)
+	ast4, _ := Parse("mytest", `{ user(filter: {a: [3, 1, 2]}, id: 1) { name } }`)
+
+	if CanonicalizeArguments(ast3).String() == CanonicalizeArguments(ast4).String() {
+		t.Error("List order should be preserved")
+		return
+	}
+}
+
+func TestMergeDocuments(t *testing.T) {
+
+	doc1, _ := Parse("doc1", `query a { user { ...userFields } } fragment userFields on User { id }`)
+	doc2, _ := Parse("doc2", `mutation b { like(id: 1) { id } }
+fragment postFields on Post { title }`)
+	doc3, _ := Parse("doc3", `{ posts { ...postFields } }`)
+
+	res, err := MergeDocuments(doc1, doc2, doc3)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if pp, err := PrettyPrint(res); err != nil || pp != `
+query a {
+  user {
+    ...userFields
+  }
+}
+
+fragment userFields on User {
+  id
+}
+
+mutation b {
+  like(id: 1) {
+    id
+  }
+}
+
+fragment postFields on Post {
+  title
+}
+
+{
+  posts {
+    ...postFields
+  }
+}`[1:] {
+		t.Error("Unexpected result:", pp, err)
+		return
+	}
+
+	if len(doc1.Children) != 2 || res.Children[0] == doc1.Children[0] || res.Children[0].Parent != res {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Detect name clashes
+
+	doc4, _ := Parse("doc4", `query c { user { id } }
+fragment userFields on User { name }`)
+
+	if res, err := MergeDocuments(doc1, doc4); err == nil || err.Error() !=
+		"Parse error in AST: Duplicate definition (fragment userFields) (Line:2 Pos:2)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := MergeDocuments(doc1, doc2, doc1); err == nil || err.Error() !=
+		"Parse error in AST: Duplicate definition (operation a) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	doc5, _ := Parse("doc5", `query { user { id } }`)
+
+	if res, err := MergeDocuments(doc3, doc5); err == nil || err.Error() !=
+		"Parse error in AST: Duplicate definition (anonymous operation) (Line:1 Pos:1)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := MergeDocuments(); err != nil || len(res.Children) != 0 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
+
+func TestAnnotateCost(t *testing.T) {
+
+	ast, err := Parse("mytest", `{
+  user(id: 1) @auth {
+    name
+    friends { id name }
+    posts @cost(value: 2) { title }
+  }
+}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(AnnotateCost(ast, map[string]int{"user": 5, "friends": 10, "posts": 7}))
+	if err != nil || res != `
+{
+  user(id: 1) @auth @cost(value: 5) {
+    name
+    friends @cost(value: 10) {
+      id
+      name
+    }
+    posts @cost(value: 2) {
+      title
+    }
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// The annotated document can be parsed again
+
+	ast2, err := Parse("mytest", res)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if v, ok := FindField(ast2.FindFirst(NodeSelectionSet), "user").DirectiveArg("cost", "value"); !ok || v != "5" {
+		t.Error("Unexpected result:", v, ok)
+		return
+	}
+
+	// Annotating again does not duplicate directives
+
+	if res2, err := PrettyPrint(AnnotateCost(ast2, map[string]int{"user": 1, "friends": 1})); err != nil || res2 != res {
+		t.Error("Unexpected result:", res2, err)
+		return
+	}
+}
+
+func TestSetArgument(t *testing.T) {
+
+	ast, err := Parse("mytest", `{ users @auth { id } posts(first: 10, after: "x") { title } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	selectionSet := ast.FindFirst(NodeSelectionSet)
+	first, _ := ParseValue("mytest", "100")
+
+	if err := SetArgument(FindField(selectionSet, "users"), "first", first); err != nil {
+		t.Error(err)
+		return
+	}
+
+	filter, _ := ParseValue("mytest", `{active: true}`)
+
+	if err := SetArgument(FindField(selectionSet, "users"), "filter", filter); err != nil {
+		t.Error(err)
+		return
+	}
+
+	first, _ = ParseValue("mytest", "100")
+
+	if err := SetArgument(FindField(selectionSet, "posts"), "first", first); err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(ast)
+	if err != nil || res != `
+{
+  users(first: 100, filter: {active : true}) @auth {
+    id
+  }
+  posts(first: 100, after: "x") {
+    title
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if first.Parent.Name != NodeArgument || first.Parent.Parent.Parent != FindField(selectionSet, "posts") {
+		t.Error("Unexpected result:", first.Parent)
+		return
+	}
+
+	// The result can be parsed again
+
+	if _, err := Parse("mytest", res); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := SetArgument(selectionSet, "first", first); err == nil || err.Error() != "Cannot set argument on SelectionSet node" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := SetArgument(FindField(selectionSet, "posts"), "first", nil); err == nil ||
+		err.Error() != "Cannot set argument first without a value" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestRemoveArgument(t *testing.T) {
+
+	ast, err := Parse("mytest", `{ users(debug: true) @auth { id } posts(first: 10, debug: true, after: "x") { title } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	selectionSet := ast.FindFirst(NodeSelectionSet)
+
+	if !RemoveArgument(FindField(selectionSet, "users"), "debug") ||
+		!RemoveArgument(FindField(selectionSet, "posts"), "debug") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	if RemoveArgument(FindField(selectionSet, "users"), "debug") ||
+		RemoveArgument(FindField(selectionSet, "posts"), "debug") ||
+		RemoveArgument(selectionSet, "debug") {
+		t.Error("Unexpected result")
+		return
+	}
+
+	res, err := PrettyPrint(ast)
+	if err != nil || res != `
+{
+  users @auth {
+    id
+  }
+  posts(first: 10, after: "x") {
+    title
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if f := FindField(selectionSet, "users"); f.FindFirst(NodeArguments) != nil {
+		t.Error("Unexpected result:", f)
+		return
+	}
+}
+
+func TestReplaceArgumentValue(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query a { user(id: 0) { friends(id: 0, first: 5) { id } } }
+query b { user(id: 0, name: "x") { id } other : user(id: 0) { id } }
+fragment f on Query { user(id: 0) { id } }
+`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	value, _ := ParseValue("mytest", "42")
+
+	// Paths consist of field names - aliases are ignored
+
+	if res := ReplaceArgumentValue(ast, "other", "id", value); res != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ReplaceArgumentValue(ast, "user", "id", value); res != 4 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ReplaceArgumentValue(ast, "user.friends", "id", value); res != 1 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ReplaceArgumentValue(ast, "user", "first", value); res != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ReplaceArgumentValue(ast, "user", "id", nil); res != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	res, err := PrettyPrint(ast)
+	if err != nil || res != `
+query a {
+  user(id: 42) {
+    friends(id: 42, first: 5) {
+      id
+    }
+  }
+}
+
+query b {
+  user(id: 42, name: "x") {
+    id
+  }
+  other : user(id: 42) {
+    id
+  }
+}
+
+fragment f on Query {
+  user(id: 42) {
+    id
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Each argument gets its own copy of the value
+
+	if value.Parent != nil || ast.FindFirst(NodeArgument).Children[1] == value {
+		t.Error("Unexpected result:", value.Parent)
+		return
+	}
+
+	if arg := ast.FindFirst(NodeArgument); arg.Children[1].Parent != arg {
+		t.Error("Unexpected result:", arg.Children[1].Parent)
+		return
+	}
+}
+
+func TestRenameField(t *testing.T) {
+
+	ast, err := Parse("mytest", `{ user(id: 1) @auth { mail : email name } }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	user := FindField(ast.FindFirst(NodeSelectionSet), "user")
+	selectionSet := user.FindFirst(NodeSelectionSet)
+
+	if err := RenameField(user, "account"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := RenameField(FindField(selectionSet, "mail"), "emailAddress"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err := RenameField(FindField(selectionSet, "name"), "fullName"); err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := PrettyPrint(ast)
+	if err != nil || res != `
+{
+  account(id: 1) @auth {
+    mail : emailAddress
+    fullName
+  }
+}`[1:] {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if err := RenameField(user, "1nvalid"); err == nil || err.Error() != "Invalid field name: 1nvalid" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+
+	if err := RenameField(selectionSet, "foo"); err == nil || err.Error() != "Cannot rename SelectionSet node" {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestFragmentMap(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+{ user { ...userFields } posts { ...postFields } }
+fragment userFields on User { id }
+fragment postFields on Post { title }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res := FragmentMap(ast)
+
+	if len(res) != 2 || res["userFields"].Children[1].Token.Val != "User" ||
+		res["postFields"].Children[1].Token.Val != "Post" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, err := FragmentMapStrict(ast); err != nil || len(res) != 2 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `
+fragment a on User { id }
+fragment b on User { name }
+fragment a on Admin { id }`)
+
+	if res := FragmentMap(ast); len(res) != 2 || res["a"].Children[1].Token.Val != "Admin" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res, err := FragmentMapStrict(ast); err == nil || res != nil || err.Error() !=
+		"Parse error in AST: Duplicate definition (fragment a) (Line:4 Pos:2)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res := FragmentMap(&ASTNode{Name: NodeDocument, Token: &LexToken{}}); len(res) != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestSplitOperations(t *testing.T) {
+
+	ast, err := Parse("mytest", `
+query a { user { ...userFields } }
+fragment userFields on User { id ...nameFields }
+fragment nameFields on User { name }
+fragment postFields on Post { title }
+query b { posts { ...postFields } }
+mutation c { like { ...nameFields } }
+fragment unused on User { id }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	res, err := SplitOperations(ast)
+	if err != nil || len(res) != 3 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	var printed []string
+
+	for _, doc := range res {
+		pp, err := PrettyPrint(doc)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		printed = append(printed, pp)
+	}
+
+	if printed[0] != `
+query a {
+  user {
+    ...userFields
+  }
+}
+
+fragment userFields on User {
+  id
+  ...nameFields
+}
+
+fragment nameFields on User {
+  name
+}`[1:] {
+		t.Error("Unexpected result:", printed[0])
+		return
+	}
+
+	if printed[1] != `
+query b {
+  posts {
+    ...postFields
+  }
+}
+
+fragment postFields on Post {
+  title
+}`[1:] {
+		t.Error("Unexpected result:", printed[1])
+		return
+	}
+
+	if printed[2] != `
+mutation c {
+  like {
+    ...nameFields
+  }
+}
+
+fragment nameFields on User {
+  name
+}`[1:] {
+		t.Error("Unexpected result:", printed[2])
+		return
+	}
+
+	// Shared fragments are copied
+
+	if res[0].Children[2] == res[2].Children[1] || res[0].Children[2].Children[0] == res[2].Children[1].Children[0] {
+		t.Error("Fragments should be copied")
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { ...a } } fragment a on User { ...b } fragment b on User { ...a }`)
+
+	if res, err := SplitOperations(ast); err == nil || err.Error() !=
+		"Parse error in AST: Cyclic fragment reference (a -> b -> a) (Line:1 Pos:71)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	ast, _ = Parse("mytest", `{ user { ...a } }`)
+
+	if res, err := SplitOperations(ast); err == nil || err.Error() !=
+		"Parse error in AST: Unknown fragment (a) (Line:1 Pos:13)" {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+}
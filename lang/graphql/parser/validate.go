@@ -0,0 +1,219 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"strings"
+
+	"github.com/krotik/common/errorutil"
+	"github.com/krotik/common/stringutil"
+)
+
+/*
+ValidationOptions are options which change the checks of Validate.
+*/
+type ValidationOptions struct {
+	AllowUnusedVariables bool // Do not report variables which are defined but never used
+	AllowFieldConflicts  bool // Do not report fields with the same response key which cannot be merged
+}
+
+/*
+Validate runs all available structural checks on a given document and
+returns all findings at once. The following problems are reported:
+
+- Unknown fragments (ErrUnknownFragment)
+- Fragments which reference each other in a cycle (ErrCyclicFragment)
+- Arguments which are given more than once (ErrDuplicateArgument)
+- Variables which are defined more than once (ErrDuplicateVariable)
+- Variables which are used but not defined (ErrUndefinedVariable)
+- Variables which are defined but not used (ErrUnusedVariable)
+- Fields in a selection set (including the fields of inline fragments and
+fragment spreads) with the same response key but different names or
+arguments (ErrFieldConflict)
+
+Each finding is an Error with the position of the offending node. Use
+HasErrors of the returned CompositeError to check if there were findings.
+*/
+func Validate(doc *ASTNode, opts ValidationOptions) *errorutil.CompositeError {
+	cerr := errorutil.NewCompositeError()
+	fragments := FragmentMap(doc)
+
+	for _, spread := range doc.FindAll(NodeFragmentSpread) {
+		if _, ok := fragments[spread.Token.Val]; !ok {
+			cerr.Add(newASTError(ErrUnknownFragment, spread.Token.Val, spread))
+		}
+	}
+
+	validateFragmentCycles(doc, fragments, cerr)
+
+	for _, args := range doc.FindAll(NodeArguments) {
+		var names []string
+
+		for _, arg := range args.Children {
+			name := arg.Children[0].Token.Val
+
+			if stringutil.IndexOf(name, names) != -1 {
+				cerr.Add(newASTError(ErrDuplicateArgument, name, arg))
+			}
+
+			names = append(names, name)
+		}
+	}
+
+	for _, op := range doc.FindAll(NodeOperationDefinition) {
+		var defined, used []string
+
+		for _, vd := range op.FindAll(NodeVariableDefinition) {
+			defined = append(defined, vd.Children[0].Token.Val)
+		}
+
+		for _, v := range usedVariables(op, fragments) {
+			if stringutil.IndexOf(v.Token.Val, defined) == -1 {
+				cerr.Add(newASTError(ErrUndefinedVariable, "$"+v.Token.Val, v))
+			}
+
+			used = append(used, v.Token.Val)
+		}
+
+		for _, err := range variableDefinitionErrors(op, used, !opts.AllowUnusedVariables) {
+			cerr.Add(err)
+		}
+	}
+
+	if !opts.AllowFieldConflicts {
+		reported := make(map[*ASTNode]bool)
+
+		for _, ss := range doc.FindAll(NodeSelectionSet) {
+			validateFieldConflicts(ss, fragments, reported, cerr)
+		}
+	}
+
+	return cerr
+}
+
+/*
+validateFragmentCycles reports all cycles between the fragment definitions of
+a given document. Each cycle is reported once.
+*/
+func validateFragmentCycles(doc *ASTNode, fragments map[string]*ASTNode, cerr *errorutil.CompositeError) {
+	var visit func(name string, stack []string)
+
+	const (
+		inProgress = 1
+		done       = 2
+	)
+
+	state := make(map[string]int)
+
+	visit = func(name string, stack []string) {
+		state[name] = inProgress
+
+		for _, spread := range fragments[name].FindAll(NodeFragmentSpread) {
+			next := spread.Token.Val
+
+			if _, ok := fragments[next]; !ok {
+				continue
+			}
+
+			if state[next] == inProgress {
+				cycle := append(stack[stringutil.IndexOf(next, stack):len(stack):len(stack)], next)
+				cerr.Add(newASTError(ErrCyclicFragment, strings.Join(cycle, " -> "), spread))
+
+			} else if state[next] != done {
+				visit(next, append(stack[:len(stack):len(stack)], next))
+			}
+		}
+
+		state[name] = done
+	}
+
+	for _, fd := range doc.FindAll(NodeFragmentDefinition) {
+		if name := fd.Children[0].Token.Val; state[name] != done {
+			visit(name, []string{name})
+		}
+	}
+}
+
+/*
+validateFieldConflicts reports all fields of a given selection set which have
+the same response key as a previous field but a different name or different
+arguments. Fields which were reported before are not reported again.
+*/
+func validateFieldConflicts(selectionSet *ASTNode, fragments map[string]*ASTNode,
+	reported map[*ASTNode]bool, cerr *errorutil.CompositeError) {
+
+	var collect func(ss *ASTNode)
+	var fields []*ASTNode
+
+	visited := make(map[string]bool)
+
+	collect = func(ss *ASTNode) {
+
+		for _, selection := range ss.Children {
+
+			switch selection.Name {
+
+			case NodeField:
+				fields = append(fields, selection)
+
+			case NodeInlineFragment:
+				collect(selection.Children[len(selection.Children)-1])
+
+			case NodeFragmentSpread:
+				if fd, ok := fragments[selection.Token.Val]; ok && !visited[selection.Token.Val] {
+					visited[selection.Token.Val] = true
+					collect(fd.Children[len(fd.Children)-1])
+				}
+			}
+		}
+	}
+
+	// Fields are identified by their name and their arguments (in any order) -
+	// the pretty printed arguments distinguish value types (e.g. "1" and 1)
+
+	signature := func(field *ASTNode) string {
+		var name, args string
+
+		for _, child := range field.Children {
+			if child.Name == NodeName {
+				name = child.Token.Val
+			} else if child.Name == NodeArguments {
+				canonical := CanonicalizeArguments(child.Clone())
+
+				if res, err := PrettyPrint(canonical); err == nil {
+					args = res
+				} else {
+					args = canonical.String()
+				}
+			}
+		}
+
+		return name + "\n" + args
+	}
+
+	collect(selectionSet)
+
+	firstFields := make(map[string]*ASTNode)
+
+	for _, field := range fields {
+		key := fieldResponseKey(field)
+
+		first, ok := firstFields[key]
+		if !ok {
+			firstFields[key] = field
+			continue
+		}
+
+		if !reported[field] && signature(field) != signature(first) {
+			reported[field] = true
+			cerr.Add(newASTError(ErrFieldConflict, key, field))
+		}
+	}
+}
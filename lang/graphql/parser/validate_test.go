@@ -0,0 +1,96 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+
+	ast, err := Parse("mytest", `query q($id: ID, $unused: Int, $id: ID) {
+  user(id: $id, id: 2) {
+    name
+    name: email
+    ...a
+    ...missing
+    friends(first: 10, after: $cursor) { id }
+  }
+}
+fragment a on User { friends(first: 5) { id } ...b }
+fragment b on User { ...a ...c }
+fragment c on User { ...c }`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	cerr := Validate(ast, ValidationOptions{})
+
+	var res []string
+	for _, err := range cerr.Errors {
+		res = append(res, err.Error())
+	}
+
+	if strings.Join(res, "\n") != `
+Parse error in AST: Unknown fragment (missing) (Line:6 Pos:9)
+Parse error in AST: Cyclic fragment reference (a -> b -> a) (Line:11 Pos:26)
+Parse error in AST: Cyclic fragment reference (c -> c) (Line:12 Pos:26)
+Parse error in AST: Duplicate argument (id) (Line:2 Pos:18)
+Parse error in AST: Undefined variable ($cursor) (Line:7 Pos:33)
+Parse error in AST: Unused variable ($unused) (Line:1 Pos:19)
+Parse error in AST: Duplicate variable ($id) (Line:1 Pos:33)
+Parse error in AST: Conflicting fields (name) (Line:4 Pos:6)
+Parse error in AST: Conflicting fields (friends) (Line:7 Pos:6)`[1:] {
+		t.Error("Unexpected result:\n" + strings.Join(res, "\n"))
+		return
+	}
+
+	for _, err := range cerr.Errors {
+		if _, ok := err.(*Error); !ok {
+			t.Error("Unexpected result:", err)
+			return
+		}
+	}
+
+	cerr = Validate(ast, ValidationOptions{AllowUnusedVariables: true, AllowFieldConflicts: true})
+
+	if len(cerr.Errors) != 6 || strings.Contains(cerr.Error(), "Unused") || strings.Contains(cerr.Error(), "Conflicting") {
+		t.Error("Unexpected result:", cerr)
+		return
+	}
+
+	// Valid documents produce no findings - fields with the same response key
+	// and the same arguments (in any order) can be merged
+
+	ast, _ = Parse("mytest", `query q($id: ID) {
+  user(id: $id, type: USER) { ...f }
+  user(type: USER, id: $id) { name }
+}
+fragment f on User { name friends { id } ... on User { friends { name } } }`)
+
+	if cerr := Validate(ast, ValidationOptions{}); cerr.HasErrors() {
+		t.Error("Unexpected result:", fmt.Sprint(cerr))
+		return
+	}
+
+	// Arguments with the same literal but a different type conflict
+
+	ast, _ = Parse("mytest", `{ a(x: "1") a(x: 1) b(x: 1) b(x: 2) c(x: A) c(x: "A") d(x: 1) d(x: 1) }`)
+
+	if cerr := Validate(ast, ValidationOptions{}); cerr.Error() != "Parse error in AST: Conflicting fields (a) (Line:1 Pos:13); "+
+		"Parse error in AST: Conflicting fields (b) (Line:1 Pos:29); "+
+		"Parse error in AST: Conflicting fields (c) (Line:1 Pos:45)" {
+		t.Error("Unexpected result:", fmt.Sprint(cerr))
+		return
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+)
+
+/*
+MaxNestingDepth is the depth of nested selection sets above which
+ParseWithWarnings produces a warning.
+*/
+const MaxNestingDepth = 10
+
+/*
+Warning models a condition in the parsed input which is not an error but
+might be unintended.
+*/
+type Warning struct {
+	Message string // Message of this warning
+	Line    int    // Line of the warning
+	Pos     int    // Position of the warning
+}
+
+/*
+String returns a human-readable string representation of this warning.
+*/
+func (w Warning) String() string {
+	return fmt.Sprintf("%v (Line:%d Pos:%d)", w.Message, w.Line, w.Pos)
+}
+
+/*
+ParseWithWarnings parses a given input string and returns an AST and a list of
+warnings. Warnings are produced for empty selection sets, selection sets which
+are nested deeper than MaxNestingDepth and arguments which are given more than
+once.
+*/
+func ParseWithWarnings(name string, input string) (*ASTNode, []Warning, error) {
+	var warnings []Warning
+	var visit func(n *ASTNode, depth int)
+
+	doc, err := Parse(name, input)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	addWarning := func(n *ASTNode, msg string, args ...interface{}) {
+		warnings = append(warnings, Warning{fmt.Sprintf(msg, args...), n.Token.Lline, n.Token.Lpos})
+	}
+
+	visit = func(n *ASTNode, depth int) {
+
+		switch n.Name {
+
+		case NodeSelectionSet:
+			depth++
+
+			if len(n.Children) == 0 {
+				addWarning(n, "Empty selection set")
+			}
+
+			if depth == MaxNestingDepth+1 {
+				addWarning(n, "Selection sets are nested deeper than %v levels", MaxNestingDepth)
+			}
+
+		case NodeArguments:
+			var names []string
+
+			for _, arg := range n.Children {
+				name := arg.Children[0].Token.Val
+
+				for _, other := range names {
+					if other == name {
+						addWarning(arg, "Argument %v is given more than once", name)
+						break
+					}
+				}
+
+				names = append(names, name)
+			}
+		}
+
+		for _, child := range n.Children {
+			visit(child, depth)
+		}
+	}
+
+	visit(doc, 0)
+
+	return doc, warnings, nil
+}
@@ -0,0 +1,59 @@
+/*
+ * Public Domain Software
+ *
+ * I (Matthias Ladkau) am the author of the source code in this file.
+ * I have placed the source code in this file in the public domain.
+ *
+ * For further information see: http://creativecommons.org/publicdomain/zero/1.0/
+ */
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseWithWarnings(t *testing.T) {
+
+	ast, warnings, err := ParseWithWarnings("mytest", `{ user { } }`)
+	if err != nil || ast == nil {
+		t.Error(err)
+		return
+	}
+
+	if fmt.Sprint(warnings) != "[Empty selection set (Line:1 Pos:8)]" {
+		t.Error("Unexpected result:", warnings)
+		return
+	}
+
+	_, warnings, err = ParseWithWarnings("mytest", `{
+  user(id: 1, name: "foo", id: 2) { name }
+  other(id: 1) { id }
+}`)
+	if err != nil || fmt.Sprint(warnings) != "[Argument id is given more than once (Line:2 Pos:29)]" {
+		t.Error("Unexpected result:", warnings, err)
+		return
+	}
+
+	// Deep nesting produces a single warning
+
+	query := strings.Repeat("{ a ", MaxNestingDepth+2) + "{ b }" + strings.Repeat(" }", MaxNestingDepth+2)
+
+	_, warnings, err = ParseWithWarnings("mytest", query)
+	if err != nil || fmt.Sprint(warnings) != "[Selection sets are nested deeper than 10 levels (Line:1 Pos:41)]" {
+		t.Error("Unexpected result:", warnings, err)
+		return
+	}
+
+	if _, warnings, err := ParseWithWarnings("mytest", `{ user { name } }`); err != nil || warnings != nil {
+		t.Error("Unexpected result:", warnings, err)
+		return
+	}
+
+	if ast, warnings, err := ParseWithWarnings("mytest", `{ user { `); err == nil || ast != nil || warnings != nil {
+		t.Error("Unexpected result:", ast, warnings, err)
+		return
+	}
+}
@@ -15,8 +15,10 @@ package stringutil
 import (
 	"bytes"
 	"crypto/md5"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"math"
 	"regexp"
 	"sort"
 	"strconv"
@@ -29,36 +31,64 @@ import (
 LongestCommonPrefix determines the longest common prefix of a given list of strings.
 */
 func LongestCommonPrefix(s []string) string {
-	var res string
 
-	commonPrefix := func(str1, str2 string) string {
-		var buf bytes.Buffer
+	if len(s) == 0 {
+		return ""
+	}
 
-		rs2 := StringToRuneSlice(str2)
-		rs2len := len(rs2)
+	first := s[0]
+	n := 0
 
-		for i, c := range str1 {
-			if i >= rs2len {
-				break
-			} else if c == rs2[i] {
-				buf.WriteRune(c)
+	// Compare all strings column-wise until the first mismatch
+
+loop:
+	for ; n < len(first); n++ {
+		for _, str := range s[1:] {
+			if n >= len(str) || str[n] != first[n] {
+				break loop
 			}
 		}
+	}
+
+	// Make sure a multi-byte rune is not cut in half
 
-		return buf.String()
+	for n > 0 && n < len(first) && !utf8.RuneStart(first[n]) {
+		n--
 	}
 
-	lens := len(s)
+	return first[:n]
+}
+
+/*
+LongestCommonSuffix determines the longest common suffix of a given list of strings.
+*/
+func LongestCommonSuffix(s []string) string {
 
-	if lens > 0 {
-		res = s[0]
+	if len(s) == 0 {
+		return ""
+	}
 
-		for i := 1; i < lens; i++ {
-			res = commonPrefix(res, s[i])
+	first := s[0]
+	n := 0
+
+	// Compare all strings column-wise from the end until the first mismatch
+
+loop:
+	for ; n < len(first); n++ {
+		for _, str := range s[1:] {
+			if n >= len(str) || str[len(str)-1-n] != first[len(first)-1-n] {
+				break loop
+			}
 		}
 	}
 
-	return res
+	// Make sure a multi-byte rune is not cut in half
+
+	for n > 0 && !utf8.RuneStart(first[len(first)-n]) {
+		n--
+	}
+
+	return first[len(first)-n:]
 }
 
 /*
@@ -78,7 +108,7 @@ func PrintStringTable(ss []string, c int) string {
 	for i, s := range ss {
 		col := i % c
 
-		if l := utf8.RuneCountInString(s); l > maxWidths[col] {
+		if l := VisibleLength(s); l > maxWidths[col] {
 			maxWidths[col] = l
 		}
 	}
@@ -87,16 +117,12 @@ func PrintStringTable(ss []string, c int) string {
 		col := i % c
 
 		if i < len(ss)-1 {
-			var formatString string
+			ret.WriteString(s)
 
 			if col != c-1 {
-				formatString = fmt.Sprintf("%%-%vv ", maxWidths[col])
-			} else {
-				formatString = "%v"
+				ret.WriteString(strings.Repeat(" ", maxWidths[col]-VisibleLength(s)+1))
 			}
 
-			ret.WriteString(fmt.Sprintf(formatString, s))
-
 		} else {
 
 			ret.WriteString(fmt.Sprintln(s))
@@ -146,6 +172,18 @@ PrintGraphicStringTable prints a given list of strings in a graphic table
 with c columns - creates a header after n rows using syms as drawing symbols.
 */
 func PrintGraphicStringTable(ss []string, c int, n int, syms *GraphicStringTableSymbols) string {
+	return PrintGraphicStringTableWrapped(ss, c, n, 0, syms)
+}
+
+/*
+PrintGraphicStringTableWrapped prints a given list of strings in a graphic table
+with c columns - creates a header after n rows using syms as drawing symbols.
+Cells which are wider than maxWidth are word wrapped into multiple lines. A
+maxWidth of 0 or less disables wrapping.
+*/
+func PrintGraphicStringTableWrapped(ss []string, c int, n int, maxWidth int,
+	syms *GraphicStringTableSymbols) string {
+
 	var topline, bottomline, middleline, ret bytes.Buffer
 
 	if c < 1 {
@@ -156,35 +194,49 @@ func PrintGraphicStringTable(ss []string, c int, n int, syms *GraphicStringTable
 		syms = MonoTable
 	}
 
-	//  Determine max widths of columns
+	// Split cells into lines
 
-	maxWidths := make(map[int]int)
+	cells := make([][]string, len(ss))
 
 	for i, s := range ss {
-		col := i % c
+		if maxWidth > 0 {
+			cells[i] = strings.Split(WordWrap(s, maxWidth), "\n")
+		} else {
+			cells[i] = []string{s}
+		}
+	}
+
+	//  Determine max widths of columns
 
-		l := utf8.RuneCountInString(s)
+	cols := c
+	if len(ss) < c {
+		cols = len(ss)
+	}
 
-		if l > maxWidths[col] {
-			maxWidths[col] = l
+	maxWidths := make([]int, cols)
+
+	for i, lines := range cells {
+		col := i % c
+
+		for _, line := range lines {
+			if l := VisibleLength(line); l > maxWidths[col] {
+				maxWidths[col] = l
+			}
 		}
 	}
 
-	// Determine total width and create top, middle and bottom line
+	// Create top, middle and bottom line
 
-	totalWidth := 1
 	topline.WriteString(syms.BoxCornerTopLeft)
 	bottomline.WriteString(syms.BoxCornerBottomLeft)
 	middleline.WriteString(syms.BoxLeftMiddle)
 
-	for i := 0; i < len(maxWidths); i++ {
-		totalWidth += maxWidths[i] + 2
-
+	for i := 0; i < cols; i++ {
 		topline.WriteString(GenerateRollingString(syms.BoxHorizontal, maxWidths[i]+1))
 		bottomline.WriteString(GenerateRollingString(syms.BoxHorizontal, maxWidths[i]+1))
 		middleline.WriteString(GenerateRollingString(syms.BoxHorizontal, maxWidths[i]+1))
 
-		if i < len(maxWidths)-1 {
+		if i < cols-1 {
 			topline.WriteString(syms.BoxTopMiddle)
 			bottomline.WriteString(syms.BoxBottomMiddle)
 			middleline.WriteString(syms.BoxMiddle)
@@ -200,41 +252,41 @@ func PrintGraphicStringTable(ss []string, c int, n int, syms *GraphicStringTable
 	ret.WriteString(topline.String())
 	ret.WriteString(fmt.Sprintln())
 
-	row := 0
-	for i, s := range ss {
-		col := i % c
+	rows := (len(ss) + c - 1) / c
 
-		ret.WriteString(syms.BoxVertical)
+	for row := 0; row < rows; row++ {
 
-		if i < len(ss)-1 {
-			formatString := fmt.Sprintf("%%-%vv ", maxWidths[col])
-			ret.WriteString(fmt.Sprintf(formatString, s))
-		} else {
-			formatString := fmt.Sprintf("%%-%vv ", maxWidths[col])
-			ret.WriteString(fmt.Sprintf(formatString, s))
+		// Determine the height of the row
+
+		height := 1
+		for col := 0; col < cols; col++ {
+			if i := row*c + col; i < len(cells) && len(cells[i]) > height {
+				height = len(cells[i])
+			}
+		}
+
+		// Draw all lines of the row - shorter cells are padded
+
+		for line := 0; line < height; line++ {
+			for col := 0; col < cols; col++ {
+				var text string
+
+				if i := row*c + col; i < len(cells) && line < len(cells[i]) {
+					text = cells[i][line]
+				}
 
-			for col < c-1 && col < len(ss)-1 {
-				col++
 				ret.WriteString(syms.BoxVertical)
-				ret.WriteString(GenerateRollingString(" ", maxWidths[col]))
-				ret.WriteString(" ")
+				ret.WriteString(text)
+				ret.WriteString(strings.Repeat(" ", maxWidths[col]-VisibleLength(text)+1))
 			}
 
 			ret.WriteString(syms.BoxVertical)
 			ret.WriteString(fmt.Sprintln())
-
-			break
 		}
 
-		if col == c-1 {
-			ret.WriteString(syms.BoxVertical)
+		if row+1 == n && row < rows-1 {
+			ret.WriteString(middleline.String())
 			ret.WriteString(fmt.Sprintln())
-			row++
-
-			if row == n {
-				ret.WriteString(middleline.String())
-				ret.WriteString(fmt.Sprintln())
-			}
 		}
 	}
 
@@ -277,26 +329,305 @@ func PrintCSVTable(ss []string, c int) string {
 	return ret.String()
 }
 
+/*
+SortTableRows sorts the rows of a given list of strings which represents a
+table with c columns (see PrintStringTable). The rows are sorted by the
+values of a given column using a given comparator which returns a negative
+number, zero or a positive number if the first value is smaller, equal or
+greater than the second value. A nil comparator compares values naturally
+(i.e. numbers within values are compared by their numeric value). The sort is
+stable and an incomplete last row is kept at the end. Returns a new list.
+*/
+func SortTableRows(ss []string, c int, sortCol int, cmp func(a, b string) int) []string {
+	ret := make([]string, len(ss))
+	copy(ret, ss)
+
+	if c < 1 || sortCol < 0 || sortCol >= c {
+		return ret
+	}
+
+	if cmp == nil {
+		cmp = naturalCompare
+	}
+
+	rows := make([][]string, len(ss)/c)
+
+	for i := range rows {
+		rows[i] = ss[i*c : (i+1)*c]
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return cmp(rows[i][sortCol], rows[j][sortCol]) < 0
+	})
+
+	for i, row := range rows {
+		copy(ret[i*c:], row)
+	}
+
+	return ret
+}
+
+/*
+naturalCompare compares two strings such that sequences of digits are
+compared by their numeric value. Returns: 0 if the strings are equal; -1 if
+the first string is smaller; 1 if the first string is greater.
+*/
+func naturalCompare(str1, str2 string) int {
+	digits := func(s string, i int) string {
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		return s[i:j]
+	}
+
+	i, j := 0, 0
+
+	for i < len(str1) && j < len(str2) {
+		d1, d2 := digits(str1, i), digits(str2, j)
+
+		if d1 != "" && d2 != "" {
+
+			// Compare numbers by their length (without leading zeros) and digits
+
+			n1, n2 := strings.TrimLeft(d1, "0"), strings.TrimLeft(d2, "0")
+
+			if len(n1) != len(n2) {
+				if len(n1) < len(n2) {
+					return -1
+				}
+				return 1
+			}
+
+			if res := strings.Compare(n1, n2); res != 0 {
+				return res
+			}
+
+			i += len(d1)
+			j += len(d2)
+
+			continue
+		}
+
+		if str1[i] != str2[j] {
+			if str1[i] < str2[j] {
+				return -1
+			}
+			return 1
+		}
+
+		i++
+		j++
+	}
+
+	if res := strings.Compare(str1[i:], str2[j:]); res != 0 {
+		return res
+	}
+
+	// Strings which only differ in leading zeros are ordered lexically
+
+	return strings.Compare(str1, str2)
+}
+
+/*
+ParseCSV parses a given CSV string (RFC 4180) into rows of columns. Fields can
+be quoted to contain commas, newlines and doubled quotes. Rows can have a
+different number of columns.
+*/
+func ParseCSV(s string) ([][]string, error) {
+	return ParseCSVWithOptions(s, false)
+}
+
+/*
+ParseCSVWithOptions parses a given CSV string (RFC 4180) into rows of columns.
+If reportRagged is set then an error is returned if rows have a different
+number of columns than the first row - all parsed rows are still returned.
+*/
+func ParseCSVWithOptions(s string, reportRagged bool) ([][]string, error) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+
+	if err == nil && reportRagged {
+		for i, row := range rows {
+			if len(row) != len(rows[0]) {
+				err = fmt.Errorf("Row %v has %v columns but expected %v", i+1,
+					len(row), len(rows[0]))
+				break
+			}
+		}
+	}
+
+	return rows, err
+}
+
+/*
+wideRunes are rune ranges which occupy two cells in a terminal (East Asian
+wide and fullwidth characters and emoji).
+*/
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1}, // Hangul Jamo
+		{0x231a, 0x231b, 1}, // Watch, hourglass
+		{0x2329, 0x232a, 1}, // Angle brackets
+		{0x23e9, 0x23ec, 1}, // Media controls
+		{0x2614, 0x2615, 1}, // Umbrella, hot beverage
+		{0x2e80, 0x303e, 1}, // CJK radicals, symbols and punctuation
+		{0x3041, 0x33ff, 1}, // Hiragana, Katakana, CJK compatibility
+		{0x3400, 0x4dbf, 1}, // CJK unified ideographs extension A
+		{0x4e00, 0x9fff, 1}, // CJK unified ideographs
+		{0xa000, 0xa4cf, 1}, // Yi
+		{0xac00, 0xd7a3, 1}, // Hangul syllables
+		{0xf900, 0xfaff, 1}, // CJK compatibility ideographs
+		{0xfe30, 0xfe4f, 1}, // CJK compatibility forms
+		{0xff00, 0xff60, 1}, // Fullwidth forms
+		{0xffe0, 0xffe6, 1}, // Fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{0x1f300, 0x1f64f, 1}, // Miscellaneous symbols, pictographs and emoticons
+		{0x1f680, 0x1f6ff, 1}, // Transport and map symbols
+		{0x1f900, 0x1f9ff, 1}, // Supplemental symbols and pictographs
+		{0x20000, 0x2fffd, 1}, // CJK unified ideographs extension B and later
+		{0x30000, 0x3fffd, 1}, // CJK unified ideographs extension G and later
+	},
+}
+
+/*
+DisplayWidth returns the number of terminal cells which are needed to display
+a given string. Wide runes (e.g. CJK characters and emoji) count as two cells,
+combining marks and other zero-width runes count as zero cells.
+*/
+func DisplayWidth(s string) int {
+	var ret int
+
+	for _, r := range s {
+		switch {
+		case r == '\u200b' || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+			// Zero-width runes and control characters do not occupy a cell
+		case unicode.Is(wideRunes, r):
+			ret += 2
+		default:
+			ret++
+		}
+	}
+
+	return ret
+}
+
 /*
 RuneSliceToString converts a slice of runes into a string.
 */
 func RuneSliceToString(buf []rune) string {
-	var sbuf bytes.Buffer
-	for _, r := range buf {
-		fmt.Fprintf(&sbuf, "%c", r)
-	}
-	return sbuf.String()
+	return string(buf)
 }
 
 /*
 StringToRuneSlice converts a string into a slice of runes.
 */
 func StringToRuneSlice(s string) []rune {
-	var buf []rune
-	for _, r := range s {
-		buf = append(buf, r)
+	return []rune(s)
+}
+
+/*
+RuneLen returns the number of runes in a given string.
+*/
+func RuneLen(s string) int {
+	return utf8.RuneCountInString(s)
+}
+
+/*
+ansiSGRPattern matches ANSI SGR (Select Graphic Rendition) escape sequences.
+*/
+var ansiSGRPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+/*
+StripANSI removes all ANSI SGR escape sequences (e.g. color codes) from a
+given string.
+*/
+func StripANSI(s string) string {
+	return ansiSGRPattern.ReplaceAllString(s, "")
+}
+
+/*
+VisibleLength returns the number of runes in a given string excluding ANSI SGR
+escape sequences. The table printers use this to align colorized cells.
+*/
+func VisibleLength(s string) int {
+	return utf8.RuneCountInString(StripANSI(s))
+}
+
+/*
+GraphemeCount returns the number of user-perceived characters (grapheme
+clusters) in a given string. This is a basic segmentation which keeps
+combining marks, variation selectors and emoji modifiers with their base
+character, joins characters which are connected by a zero width joiner,
+pairs regional indicators (flags) and treats CRLF as a single character.
+*/
+func GraphemeCount(s string) int {
+	var count, regionalIndicators int
+	var prev rune
+
+	isRegionalIndicator := func(r rune) bool {
+		return r >= 0x1F1E6 && r <= 0x1F1FF
+	}
+
+	for i, r := range s {
+		isNew := true
+
+		if i > 0 {
+			switch {
+			case prev == '\r' && r == '\n':
+				isNew = false
+			case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+				isNew = false
+			case r == 0x200D || prev == 0x200D:
+				isNew = false // Zero width joiner
+			case r >= 0x1F3FB && r <= 0x1F3FF:
+				isNew = false // Emoji skin tone modifier
+			case isRegionalIndicator(r) && regionalIndicators%2 == 1:
+				isNew = false
+			}
+		}
+
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+
+		if isNew {
+			count++
+		}
+
+		prev = r
+	}
+
+	return count
+}
+
+/*
+SubstringRunes returns a substring of a given string. The start index and the
+length are given in runes. Out-of-range values are clamped to the bounds of
+the string.
+*/
+func SubstringRunes(s string, start, length int) string {
+	rs := StringToRuneSlice(s)
+	l := len(rs)
+
+	if start < 0 {
+		start = 0
+	} else if start > l {
+		start = l
 	}
-	return buf
+
+	if length < 0 {
+		length = 0
+	} else if length > l-start {
+		length = l - start
+	}
+
+	return string(rs[start : start+length])
 }
 
 /*
@@ -310,6 +641,128 @@ func Plural(l int) string {
 	return ""
 }
 
+/*
+Ordinal returns a given number with its English ordinal suffix (e.g. 1st,
+2nd, 3rd, 4th, 11th).
+*/
+func Ordinal(n int) string {
+	suffix := "th"
+
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+
+	if abs%100 < 11 || abs%100 > 13 {
+		switch abs % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+
+	return fmt.Sprint(n, suffix)
+}
+
+/*
+HumanizeBytes returns a human-readable representation of a given number of
+bytes (e.g. 1.5 KB). Uses 1024-based units (KiB, MiB, ...) if binary is set
+and 1000-based units (KB, MB, ...) otherwise.
+*/
+func HumanizeBytes(n int64, binary bool) string {
+	base := 1000.0
+	units := []string{"KB", "MB", "GB", "TB", "PB", "EB"}
+
+	if binary {
+		base = 1024.0
+		units = []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	}
+
+	sign := ""
+	val := float64(n)
+
+	if val < 0 {
+		sign = "-"
+		val = -val
+	}
+
+	if val < base {
+		return fmt.Sprintf("%v%v B", sign, int64(val))
+	}
+
+	unit := -1
+
+	// Select the unit - make sure that rounding never produces a value like
+	// 1000.0 KB
+
+	for unit < len(units)-1 && math.Round(val*10)/10 >= base {
+		val /= base
+		unit++
+	}
+
+	return fmt.Sprintf("%v%.1f %v", sign, val, units[unit])
+}
+
+// Roman numeral symbols and their values in descending order
+//
+var romanNumerals = []struct {
+	value  int
+	symbol string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+	{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+	{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+/*
+ToRoman converts a given number between 1 and 3999 into a Roman numeral.
+*/
+func ToRoman(n int) (string, error) {
+	var buf bytes.Buffer
+
+	if n < 1 || n > 3999 {
+		return "", fmt.Errorf("Number out of range for Roman numeral: %v", n)
+	}
+
+	for _, rn := range romanNumerals {
+		for n >= rn.value {
+			buf.WriteString(rn.symbol)
+			n -= rn.value
+		}
+	}
+
+	return buf.String(), nil
+}
+
+/*
+FromRoman converts a given Roman numeral into a number. Only numerals in
+standard subtractive notation are accepted (e.g. "IIII" or "VV" are invalid).
+*/
+func FromRoman(s string) (int, error) {
+	var ret int
+
+	str := strings.ToUpper(s)
+	rest := str
+
+	for _, rn := range romanNumerals {
+		for strings.HasPrefix(rest, rn.symbol) {
+			ret += rn.value
+			rest = rest[len(rn.symbol):]
+		}
+	}
+
+	// Check that the numeral was in canonical form
+
+	if canonical, err := ToRoman(ret); rest != "" || err != nil || canonical != str {
+		return 0, fmt.Errorf("Invalid Roman numeral: %v", s)
+	}
+
+	return ret, nil
+}
+
 /*
 GlobParseError describes a failure to parse a glob expression
 and gives the offending expression.
@@ -524,16 +977,16 @@ func VersionStringCompare(str1, str2 string) int {
 	return 0
 }
 
+var versionStringPartPattern = regexp.MustCompile("^([0-9]+)([\\D].*)?")
+
 /*
 versionStringPartCompare compares two version string parts. Returns: 0 if the
 strings are equal; -1 if the first string is smaller; 1 if the first string is
 greater.
 */
 func versionStringPartCompare(str1, str2 string) int {
-	pat := regexp.MustCompile("^([0-9]+)([\\D].*)?")
-
-	res1 := pat.FindStringSubmatch(str1)
-	res2 := pat.FindStringSubmatch(str2)
+	res1 := versionStringPartPattern.FindStringSubmatch(str1)
+	res2 := versionStringPartPattern.FindStringSubmatch(str2)
 
 	switch {
 	case res1 == nil && res2 == nil:
@@ -575,8 +1028,13 @@ func versionStringPartCompare(str1, str2 string) int {
 IsAlphaNumeric checks if a string contains only alpha numerical characters or "_".
 */
 func IsAlphaNumeric(str string) bool {
-	ret, _ := regexp.MatchString("^[a-zA-Z0-9_]*$", str)
-	return ret
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
 }
 
 /*
@@ -588,6 +1046,100 @@ func IsTrueValue(str string) bool {
 		str == "1" || str == "active" || str == "enabled"
 }
 
+/*
+TrueValues is the default set of lower case strings which IsTrueValueCustom
+accepts as true values. Applications can add their own values (e.g. locale
+specific words).
+*/
+var TrueValues = []string{"true", "yes", "on", "ok", "1", "active", "enabled"}
+
+/*
+IsTrueValueCustom checks if a given string is a true value. The string is
+compared case-insensitive with the values in TrueValues and the given extra
+values.
+*/
+func IsTrueValueCustom(str string, extra ...string) bool {
+
+	for _, values := range [][]string{TrueValues, extra} {
+		for _, v := range values {
+			if strings.EqualFold(str, v) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+IsFalseValue checks if a given string is a false value.
+*/
+func IsFalseValue(str string) bool {
+	str = strings.ToLower(str)
+	return str == "false" || str == "no" || str == "off" || str == "0" ||
+		str == "inactive" || str == "disabled"
+}
+
+/*
+ParseBool interprets a given string as a boolean value. Returns the value and
+a flag if the string was recognized as either a true or a false value.
+*/
+func ParseBool(str string) (bool, bool) {
+	if IsTrueValue(str) {
+		return true, true
+	} else if IsFalseValue(str) {
+		return false, true
+	}
+	return false, false
+}
+
+/*
+ParseFlags parses a list of flags which are separated by sep (e.g.
+"read,write,-delete") into a set. Flags which are prefixed with - are set to
+false, all other flags are set to true. Surrounding whitespace and empty
+items are ignored. If a flag is given more than once the last item wins.
+*/
+func ParseFlags(s string, sep string) map[string]bool {
+	ret := make(map[string]bool)
+
+	for _, item := range strings.Split(s, sep) {
+		item = strings.TrimSpace(item)
+
+		if strings.HasPrefix(item, "-") {
+			if item = strings.TrimSpace(item[1:]); item != "" {
+				ret[item] = false
+			}
+		} else if item != "" {
+			ret[item] = true
+		}
+	}
+
+	return ret
+}
+
+/*
+FormatFlags creates a list of flags which are separated by sep from a given
+set. The flags are sorted by name and flags which are false are prefixed with
+-. This is the inverse of ParseFlags.
+*/
+func FormatFlags(m map[string]bool, sep string) string {
+	names := make([]string, 0, len(m))
+
+	for k := range m {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	for i, name := range names {
+		if !m[name] {
+			names[i] = "-" + name
+		}
+	}
+
+	return strings.Join(names, sep)
+}
+
 /*
 IndexOf returns the index of str in slice or -1 if it does not exist.
 */
@@ -601,6 +1153,74 @@ func IndexOf(str string, slice []string) int {
 	return -1
 }
 
+/*
+Dedup returns a copy of a given slice with all duplicates removed. The order
+of the first occurrences is preserved.
+*/
+func Dedup(s []string) []string {
+	return dedup(s, func(str string) string { return str })
+}
+
+/*
+DedupFold returns a copy of a given slice with all case-insensitive duplicates
+removed. The first occurrence (and its case) is kept.
+*/
+func DedupFold(s []string) []string {
+	return dedup(s, strings.ToLower)
+}
+
+/*
+dedup removes all strings from a given slice which have the same key as a
+previous string.
+*/
+func dedup(s []string, key func(string) string) []string {
+	ret := make([]string, 0, len(s))
+	seen := make(map[string]bool)
+
+	for _, str := range s {
+		if k := key(str); !seen[k] {
+			seen[k] = true
+			ret = append(ret, str)
+		}
+	}
+
+	return ret
+}
+
+/*
+DiffSlices compares two slices and returns the elements of b which are not in
+a (added) and the elements of a which are not in b (removed). Both results
+keep the order of their source slice. Duplicates are counted - an element
+which is twice in b but only once in a is added once.
+*/
+func DiffSlices(a, b []string) (added, removed []string) {
+	return subtractSlice(b, a), subtractSlice(a, b)
+}
+
+/*
+subtractSlice returns the elements of a which are not matched by an element
+of b. Each element of b matches at most one element of a.
+*/
+func subtractSlice(a, b []string) []string {
+	var ret []string
+
+	counts := make(map[string]int)
+
+	for _, str := range b {
+		counts[str]++
+	}
+
+	for _, str := range a {
+		if counts[str] > 0 {
+			counts[str]--
+		} else {
+			ret = append(ret, str)
+		}
+	}
+
+	return ret
+}
+
 /*
 MapKeys returns the keys of a map as a sorted list.
 */
@@ -622,6 +1242,12 @@ GenerateRollingString creates a string by repeating a given string pattern.
 func GenerateRollingString(seq string, size int) string {
 	var buf bytes.Buffer
 
+	// Fast path for single rune sequences (e.g. spaces or box characters)
+
+	if size > 0 && utf8.RuneCountInString(seq) == 1 {
+		return strings.Repeat(seq, size)
+	}
+
 	rs := StringToRuneSlice(seq)
 	l := len(rs)
 
@@ -750,62 +1376,77 @@ CamelCaseSplit splits a camel case string into a slice.
 func CamelCaseSplit(src string) []string {
 	var result []string
 
+	CamelCaseSplitFunc(src, func(word string) {
+		result = append(result, word)
+	})
+
+	return result
+}
+
+/*
+CamelCaseSplitFunc splits a camel case string and calls a given function for
+each word. Unlike CamelCaseSplit the words are not collected in memory.
+*/
+func CamelCaseSplitFunc(src string, emit func(word string)) {
+
 	if !utf8.ValidString(src) {
-		result = []string{src}
-
-	} else {
-
-		type rType int
-		const (
-			undefined rType = iota
-			lower
-			upper
-			digit
-			other
-		)
-
-		var current, previous rType
-		var runes [][]rune
-
-		for _, r := range src {
-			if unicode.IsLower(r) {
-				current = lower
-			} else if unicode.IsUpper(r) {
-				current = upper
-			} else if unicode.IsDigit(r) {
-				current = digit
-			} else {
-				current = other
-			}
+		emit(src)
+		return
+	}
 
-			if current == previous {
-				runes[len(runes)-1] = append(runes[len(runes)-1], r)
-			} else {
-				runes = append(runes, []rune{r})
-				previous = current
-			}
+	type rType int
+	const (
+		undefined rType = iota
+		lower
+		upper
+		digit
+		other
+	)
+
+	var current, previous rType
+	var word []rune
+
+	for _, r := range src {
+		if unicode.IsLower(r) {
+			current = lower
+		} else if unicode.IsUpper(r) {
+			current = upper
+		} else if unicode.IsDigit(r) {
+			current = digit
+		} else {
+			current = other
 		}
 
-		for i := 0; i < len(runes)-1; i++ {
+		if current == previous {
+			word = append(word, r)
+			continue
+		}
+
+		if previous == upper && current == lower {
 
 			// Detect cases like "ROCKH" "ard" and correct them to
 			// "ROCK" "Hard"
 
-			if unicode.IsUpper(runes[i][0]) && unicode.IsLower(runes[i+1][0]) {
-
-				runes[i+1] = append([]rune{runes[i][len(runes[i])-1]}, runes[i+1]...)
-				runes[i] = runes[i][:len(runes[i])-1]
+			last := word[len(word)-1]
+			if len(word) > 1 {
+				emit(string(word[:len(word)-1]))
 			}
-		}
+			word = []rune{last, r}
+
+		} else {
 
-		for _, s := range runes {
-			if len(s) > 0 {
-				result = append(result, string(s))
+			if len(word) > 0 {
+				emit(string(word))
 			}
+			word = []rune{r}
 		}
+
+		previous = current
 	}
 
-	return result
+	if len(word) > 0 {
+		emit(string(word))
+	}
 }
 
 /*
@@ -849,3 +1490,38 @@ func ChunkSplit(s string, size int, spaceSplit bool) []string {
 
 	return res
 }
+
+/*
+ChunkSplitBytes splits a string into chunks of at most a defined number of
+bytes. Chunks are only split at rune boundaries so a multi-byte rune is never
+cut in half. A chunk can only exceed the given size if it contains a single
+rune which is larger than the size.
+*/
+func ChunkSplitBytes(s string, size int) []string {
+	var res []string
+
+	if size <= 0 || size >= len(s) {
+		return []string{s}
+	}
+
+	for len(s) > size {
+		n := size
+
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(s)
+		}
+
+		res = append(res, s[:n])
+		s = s[n:]
+	}
+
+	if len(s) > 0 {
+		res = append(res, s)
+	}
+
+	return res
+}
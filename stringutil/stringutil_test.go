@@ -12,10 +12,13 @@ package stringutil
 import (
 	"bytes"
 	"fmt"
+	"math"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestLongestCommonPrefix(t *testing.T) {
@@ -49,6 +52,73 @@ func TestLongestCommonPrefix(t *testing.T) {
 		t.Error("Unexpected result:", res)
 		return
 	}
+
+	if res := LongestCommonPrefix([]string{"abc", "axc"}); res != "a" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// "€" and "₭" share their first two bytes
+
+	if res := LongestCommonPrefix([]string{"a😀€1", "a😀₭2", "a😀€3"}); res != "a😀" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := LongestCommonPrefix([]string{"a😀€", "a😀€1"}); res != "a😀€" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestLongestCommonSuffix(t *testing.T) {
+
+	if res := LongestCommonSuffix([]string{}); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := LongestCommonSuffix([]string{"test"}); res != "test" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := LongestCommonSuffix([]string{"report.tar.gz", "backup.tar.gz", "x.gz"}); res != ".gz" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := LongestCommonSuffix([]string{"foo", "test"}); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := LongestCommonSuffix([]string{"1€😀", "2€😀", "€😀"}); res != "€😀" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// "Ā" (C4 80) and "Ȁ" (C8 80) share their last byte
+
+	if res := LongestCommonSuffix([]string{"xĀa", "yȀa"}); res != "a" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func BenchmarkLongestCommonPrefix(b *testing.B) {
+	s := []string{
+		"/home/user/projects/common/stringutil/stringutil.go",
+		"/home/user/projects/common/stringutil/transform.go",
+		"/home/user/projects/common/stringutil/stringutil_test.go",
+		"/home/user/projects/common/stringutil/transform_test.go",
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		LongestCommonPrefix(s)
+	}
 }
 
 func TestPrintStringTable(t *testing.T) {
@@ -78,6 +148,207 @@ te
 	}
 }
 
+func TestStripANSI(t *testing.T) {
+	red := "\x1b[31mred\x1b[0m"
+	bold := "\x1b[1;32mgrün\x1b[m"
+
+	if res := StripANSI(red + " and " + bold); res != "red and grün" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := VisibleLength(red); res != 3 || len(red) != 12 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := VisibleLength(bold); res != 4 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := VisibleLength("plain"); res != 5 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Colorized cells are aligned like plain cells
+
+	if res := StripANSI(PrintStringTable([]string{red, "x", "y", "z"}, 2)); res != `
+red x
+y   z
+`[1:] {
+		t.Error("Unexpected result:\n", "#"+res+"#")
+		return
+	}
+
+	if res := StripANSI(PrintGraphicStringTable([]string{red, "x", "yyyy", "z"}, 2, 1, MonoTable)); res != `
+##########
+#red  #x #
+##########
+#yyyy #z #
+##########
+`[1:] {
+		t.Error("Unexpected result:\n", "#"+res+"#")
+		return
+	}
+}
+
+func TestPrintGraphicStringTableWrapped(t *testing.T) {
+
+	test1 := []string{"Name", "Description", "foo", "A long description of foo", "bar", "Short"}
+
+	if res := PrintGraphicStringTableWrapped(test1, 2, 1, 15, SingleLineTable); res != `
+┌─────┬───────────────┐
+│Name │Description    │
+├─────┼───────────────┤
+│foo  │A long         │
+│     │description of │
+│     │foo            │
+│bar  │Short          │
+└─────┴───────────────┘
+`[1:] {
+		t.Error("Unexpected result:\n", "#\n"+res+"#")
+		return
+	}
+
+	test1 = []string{"key", "A value which is too long", "x"}
+
+	if res := PrintGraphicStringTableWrapped(test1, 2, 5, 16, nil); res != `
+########################
+#key #A value which is #
+#    #too long         #
+#x   #                 #
+########################
+`[1:] {
+		t.Error("Unexpected result:\n", "#\n"+res+"#")
+		return
+	}
+
+	// No wrapping without a max width
+
+	if res := PrintGraphicStringTableWrapped(test1, 2, 5, 0, nil); res != PrintGraphicStringTable(test1, 2, 5, nil) {
+		t.Error("Unexpected result:\n", "#\n"+res+"#")
+		return
+	}
+}
+
+func TestSortTableRows(t *testing.T) {
+	table := []string{
+		"Name", "Size", "Type",
+		"foo", "100", "file",
+		"bar", "9", "dir",
+		"baz", "20", "file",
+		"qux", "9", "link",
+	}
+
+	// Sort by the numeric second column (without the header)
+
+	res := SortTableRows(table[3:], 3, 1, nil)
+
+	if fmt.Sprint(res) != "[bar 9 dir qux 9 link baz 20 file foo 100 file]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if fmt.Sprint(table[3:6]) != "[foo 100 file]" {
+		t.Error("Input should not be modified:", table)
+		return
+	}
+
+	res = SortTableRows(table[3:], 3, 2, func(a, b string) int {
+		return strings.Compare(b, a)
+	})
+
+	if fmt.Sprint(res) != "[qux 9 link foo 100 file baz 20 file bar 9 dir]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Incomplete rows and invalid columns
+
+	if res := SortTableRows([]string{"b", "2", "a", "10", "c"}, 2, 1, nil); fmt.Sprint(res) != "[b 2 a 10 c]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SortTableRows([]string{"b", "a"}, 1, 1, nil); fmt.Sprint(res) != "[b a]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SortTableRows([]string{"b", "a"}, 0, 0, nil); fmt.Sprint(res) != "[b a]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	for _, test := range [][]interface{}{
+		{"a2", "a10", -1}, {"a10", "a2", 1}, {"a02", "a2", -1}, {"a2b", "a2b", 0},
+		{"x9y", "x9z", -1}, {"abc", "ab", 1}, {"", "1", -1}, {"10", "9", 1},
+	} {
+		if res := naturalCompare(test[0].(string), test[1].(string)); res != test[2] {
+			t.Error("Unexpected result:", test, res)
+			return
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+
+	res, err := ParseCSV(`name,description,count
+foo,"A ""quoted"" description, with comma",1
+bar,"Multi
+line",2
+`)
+
+	if err != nil || fmt.Sprintf("%q", res) != `[["name" "description" "count"] `+
+		`["foo" "A \"quoted\" description, with comma" "1"] ["bar" "Multi\nline" "2"]]` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	// Ragged rows are allowed by default
+
+	input := "a,b,c\r\nd,e\r\nf,g,h\r\n"
+
+	if res, err := ParseCSV(input); err != nil || fmt.Sprintf("%q", res) != `[["a" "b" "c"] ["d" "e"] ["f" "g" "h"]]` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseCSVWithOptions(input, true); err == nil || err.Error() != "Row 2 has 2 columns but expected 3" ||
+		fmt.Sprintf("%q", res) != `[["a" "b" "c"] ["d" "e"] ["f" "g" "h"]]` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseCSVWithOptions("a,b\nc,d", true); err != nil || fmt.Sprintf("%q", res) != `[["a" "b"] ["c" "d"]]` {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if res, err := ParseCSV(""); err != nil || len(res) != 0 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	if _, err := ParseCSV(`a,"b`); err == nil {
+		t.Error("Unexpected result:", err)
+		return
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	testdata := []string{"", "hello", "日本語", "ｈｉ", "한국", "e\u0301", "a\u200bb", "😀!", "äöü", "\t"}
+	expected := []int{0, 5, 6, 4, 4, 1, 2, 3, 3, 0}
+
+	for i, s := range testdata {
+		if res := DisplayWidth(s); res != expected[i] {
+			t.Error("Unexpected result for", s, ":", res, "expected:", expected[i])
+		}
+	}
+}
+
 func TestRuneSlice(t *testing.T) {
 	sl := StringToRuneSlice("test")
 
@@ -90,6 +361,94 @@ func TestRuneSlice(t *testing.T) {
 		t.Error("Unexpected result:", sl)
 		return
 	}
+
+	if res := RuneSliceToString(StringToRuneSlice("a😀b€c")); res != "a😀b€c" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := RuneSliceToString([]rune{'a', 0xD800, 'b'}); res != "a\uFFFDb" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func BenchmarkRuneSlice(b *testing.B) {
+	s := strings.Repeat("Test string with some unicode 😀€ ", 20)
+
+	for i := 0; i < b.N; i++ {
+		RuneSliceToString(StringToRuneSlice(s))
+	}
+}
+
+func TestGraphemeCount(t *testing.T) {
+	testdata := []string{
+		"",
+		"abc",
+		"e\u0301te\u0301",      // Combining acute accents
+		"a\u0308\u0323b",       // Multiple combining marks
+		"\U0001F1E9\U0001F1EA", // Flag (DE)
+		"\U0001F1E9\U0001F1EA\U0001F1EB\U0001F1F7\U0001F1FA", // Two flags and a single indicator
+		"\U0001F44D\U0001F3FD!",                              // Emoji with skin tone modifier
+		"\U0001F468\u200D\U0001F469\u200D\U0001F467",         // ZWJ family sequence
+		"\u2764\uFE0F", // Variation selector
+		"a\r\nb\n",
+		"\u0301a", // Combining mark without base
+	}
+	expected := []int{0, 3, 3, 2, 1, 3, 2, 1, 1, 4, 2}
+
+	for i, str := range testdata {
+		if res := GraphemeCount(str); res != expected[i] {
+			t.Error("Unexpected result:", fmt.Sprintf("%q", str), res, "expected:", expected[i])
+			return
+		}
+	}
+
+	if RuneLen("e\u0301") != 2 || GraphemeCount("e\u0301") != 1 {
+		t.Error("Unexpected result")
+		return
+	}
+}
+
+func TestSubstringRunes(t *testing.T) {
+	test := "a😀b€c"
+
+	if res := RuneLen(test); res != 5 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SubstringRunes(test, 1, 3); res != "😀b€" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SubstringRunes(test, 3, 100); res != "€c" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Very large lengths must not overflow
+
+	if res := SubstringRunes(test, 1, int(^uint(0)>>1)); res != "😀b€c" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SubstringRunes(test, -2, 2); res != "a😀" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SubstringRunes(test, 10, 2); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := SubstringRunes(test, 2, -1); res != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
 }
 
 func TestPluralCompareByteArray(t *testing.T) {
@@ -108,6 +467,71 @@ func TestPluralCompareByteArray(t *testing.T) {
 	}
 }
 
+func TestOrdinal(t *testing.T) {
+	testdata := []int{0, 1, 2, 3, 4, 10, 11, 12, 13, 14, 21, 22, 23, 101, 111, 112, 1013, -1, -12, -22}
+	expected := []string{"0th", "1st", "2nd", "3rd", "4th", "10th", "11th", "12th", "13th", "14th",
+		"21st", "22nd", "23rd", "101st", "111th", "112th", "1013th", "-1st", "-12th", "-22nd"}
+
+	for i, n := range testdata {
+		if res := Ordinal(n); res != expected[i] {
+			t.Error("Unexpected result:", res, "expected:", expected[i])
+		}
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	testdata := []int64{0, 999, 1000, 1023, 1024, 1500000, 999999, -1500, 1<<60 + 1<<59, math.MaxInt64}
+	expectedDecimal := []string{"0 B", "999 B", "1.0 KB", "1.0 KB", "1.0 KB", "1.5 MB",
+		"1.0 MB", "-1.5 KB", "1.7 EB", "9.2 EB"}
+	expectedBinary := []string{"0 B", "999 B", "1000 B", "1023 B", "1.0 KiB", "1.4 MiB",
+		"976.6 KiB", "-1.5 KiB", "1.5 EiB", "8.0 EiB"}
+
+	for i, n := range testdata {
+		if res := HumanizeBytes(n, false); res != expectedDecimal[i] {
+			t.Error("Unexpected result:", res, "expected:", expectedDecimal[i])
+		}
+		if res := HumanizeBytes(n, true); res != expectedBinary[i] {
+			t.Error("Unexpected result:", res, "expected:", expectedBinary[i])
+		}
+	}
+}
+
+func TestRomanNumerals(t *testing.T) {
+
+	for n, r := range map[int]string{1: "I", 4: "IV", 9: "IX", 14: "XIV", 40: "XL",
+		90: "XC", 400: "CD", 1994: "MCMXCIV", 2024: "MMXXIV", 3999: "MMMCMXCIX"} {
+
+		if res, err := ToRoman(n); err != nil || res != r {
+			t.Error("Unexpected result:", n, res, err)
+			return
+		}
+
+		if res, err := FromRoman(r); err != nil || res != n {
+			t.Error("Unexpected result:", r, res, err)
+			return
+		}
+	}
+
+	if res, err := FromRoman("mcmxciv"); err != nil || res != 1994 {
+		t.Error("Unexpected result:", res, err)
+		return
+	}
+
+	for _, n := range []int{0, -1, 4000} {
+		if _, err := ToRoman(n); err == nil || err.Error() != fmt.Sprint("Number out of range for Roman numeral: ", n) {
+			t.Error("Unexpected result:", err)
+			return
+		}
+	}
+
+	for _, r := range []string{"", "IIII", "VV", "IC", "XM", "MMMM", "ABC", "IVI"} {
+		if _, err := FromRoman(r); err == nil || err.Error() != "Invalid Roman numeral: "+r {
+			t.Error("Unexpected result:", r, err)
+			return
+		}
+	}
+}
+
 func TestGlobToRegex(t *testing.T) {
 	globMatch(t, true, "*", "^$", "foo", "bar")
 	globMatch(t, true, "?", "?", "^", "[", "]", "$")
@@ -227,6 +651,21 @@ func TestVersionStringCompare(t *testing.T) {
 	}
 }
 
+func BenchmarkVersionStringSort(b *testing.B) {
+	versions := []string{"1.674.2.18", "2.4.18.14smp", "1.2.3a1", "5.4.3.2.1",
+		"1.674.2", "2.4.18.15smp", "1.2.3b1", "1.1", "2.18.15smp", "1.674.2.5"}
+	buf := make([]string, len(versions))
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		copy(buf, versions)
+		sort.Slice(buf, func(x, y int) bool {
+			return VersionStringCompare(buf[x], buf[y]) < 0
+		})
+	}
+}
+
 func TestVersionStringPartCompare(t *testing.T) {
 
 	testdata1 := []string{"", "", "1", "1", "a", "1a", "a", "1a", "1a", "1", "12a", "12a1",
@@ -246,8 +685,8 @@ func TestVersionStringPartCompare(t *testing.T) {
 }
 
 func TestIsAlphaNumeric(t *testing.T) {
-	testdata := []string{"test", "123test", "test1234_123", "test#", "test-"}
-	expected := []bool{true, true, true, false, false}
+	testdata := []string{"test", "123test", "test1234_123", "test#", "test-", "", "tést"}
+	expected := []bool{true, true, true, false, false, true, false}
 
 	for i, str := range testdata {
 		if IsAlphaNumeric(str) != expected[i] {
@@ -256,6 +695,12 @@ func TestIsAlphaNumeric(t *testing.T) {
 	}
 }
 
+func BenchmarkIsAlphaNumeric(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		IsAlphaNumeric("test1234_123_someLongerIdentifier")
+	}
+}
+
 func TestIsTrueValue(t *testing.T) {
 	testdata := []string{"1", "ok", "1", "FaLse", "0"}
 	expected := []bool{true, true, true, false, false}
@@ -267,6 +712,94 @@ func TestIsTrueValue(t *testing.T) {
 	}
 }
 
+func TestIsTrueValueCustom(t *testing.T) {
+	testdata := []string{"1", "OK", "Enabled", "ja", "OUI", "FaLse", "0", ""}
+	expected := []bool{true, true, true, false, false, false, false, false}
+
+	for i, str := range testdata {
+		if IsTrueValueCustom(str) != expected[i] {
+			t.Error("Unexpected result for true value test:", str)
+			return
+		}
+	}
+
+	expected = []bool{true, true, true, true, true, false, false, false}
+
+	for i, str := range testdata {
+		if IsTrueValueCustom(str, "ja", "oui") != expected[i] {
+			t.Error("Unexpected result for true value test:", str)
+			return
+		}
+	}
+
+	// Change the default set
+
+	defer func(values []string) {
+		TrueValues = values
+	}(TrueValues)
+
+	TrueValues = append(TrueValues, "Si")
+
+	if !IsTrueValueCustom("si") || !IsTrueValueCustom("SI", "ja") || IsTrueValue("si") {
+		t.Error("Unexpected result")
+		return
+	}
+}
+
+func TestIsFalseValue(t *testing.T) {
+	testdata := []string{"0", "no", "Off", "FaLse", "DISABLED", "inactive", "1", "maybe", ""}
+	expected := []bool{true, true, true, true, true, true, false, false, false}
+
+	for i, str := range testdata {
+		if IsFalseValue(str) != expected[i] {
+			t.Error("Unexpected result for false value test:", str)
+		}
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	testdata := []string{"TRUE", "Yes", "enabled", "FaLse", "off", "0", "maybe", "", "2"}
+	expectedValue := []bool{true, true, true, false, false, false, false, false, false}
+	expectedOk := []bool{true, true, true, true, true, true, false, false, false}
+
+	for i, str := range testdata {
+		if res, ok := ParseBool(str); res != expectedValue[i] || ok != expectedOk[i] {
+			t.Error("Unexpected result for parse bool test:", str, res, ok)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+
+	res := ParseFlags("read,write,-delete", ",")
+	if fmt.Sprint(res) != "map[delete:false read:true write:true]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := FormatFlags(res, ","); res != "-delete,read,write" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// Round trip
+
+	if res := FormatFlags(ParseFlags("-delete,read,write", ","), ","); res != "-delete,read,write" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ParseFlags(" a | - b || -c | a |-|c ", "|"); fmt.Sprint(res) != "map[a:true b:false c:true]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ParseFlags("", ","); len(res) != 0 || FormatFlags(res, ",") != "" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func TestIndexOf(t *testing.T) {
 	slice := []string{"foo", "bar", "test"}
 
@@ -288,6 +821,73 @@ func TestIndexOf(t *testing.T) {
 	}
 }
 
+func TestDedup(t *testing.T) {
+
+	if res := Dedup([]string{"b", "a", "b", "c", "a", "B"}); fmt.Sprint(res) != "[b a c B]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := DedupFold([]string{"b", "a", "B", "c", "A", "Straße", "STRASSE", "straße"}); fmt.Sprint(res) != "[b a c Straße STRASSE]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := Dedup(nil); res == nil || len(res) != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := DedupFold([]string{}); res == nil || len(res) != 0 {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// The input is not modified
+
+	input := []string{"x", "x", "y"}
+
+	if res := Dedup(input); fmt.Sprint(res, input) != "[x y] [x x y]" {
+		t.Error("Unexpected result:", res, input)
+		return
+	}
+}
+
+func TestDiffSlices(t *testing.T) {
+
+	added, removed := DiffSlices([]string{"a", "b", "c", "d"}, []string{"e", "d", "b", "f"})
+	if fmt.Sprint(added, removed) != "[e f] [a c]" {
+		t.Error("Unexpected result:", added, removed)
+		return
+	}
+
+	added, removed = DiffSlices([]string{"a", "b"}, []string{"c", "d"})
+	if fmt.Sprint(added, removed) != "[c d] [a b]" {
+		t.Error("Unexpected result:", added, removed)
+		return
+	}
+
+	added, removed = DiffSlices([]string{"a", "b"}, []string{"b", "a"})
+	if added != nil || removed != nil {
+		t.Error("Unexpected result:", added, removed)
+		return
+	}
+
+	// Duplicates are counted
+
+	added, removed = DiffSlices([]string{"x", "y", "y", "y"}, []string{"x", "x", "y"})
+	if fmt.Sprint(added, removed) != "[x] [y y]" {
+		t.Error("Unexpected result:", added, removed)
+		return
+	}
+
+	added, removed = DiffSlices(nil, []string{"a"})
+	if fmt.Sprint(added, removed) != "[a] []" || removed != nil {
+		t.Error("Unexpected result:", added, removed)
+		return
+	}
+}
+
 func TestMapKeys(t *testing.T) {
 	testMap := map[string]interface{}{
 		"1": "2",
@@ -302,9 +902,9 @@ func TestMapKeys(t *testing.T) {
 }
 
 func TestGenerateRollingString(t *testing.T) {
-	testdata := []string{"_-=-_", "abc", "=", ""}
-	testlen := []int{20, 4, 5, 100}
-	expected := []string{"_-=-__-=-__-=-__-=-_", "abca", "=====", ""}
+	testdata := []string{"_-=-_", "abc", "=", "", "─", "€😀", "=", "="}
+	testlen := []int{20, 4, 5, 100, 3, 3, 0, -1}
+	expected := []string{"_-=-__-=-__-=-__-=-_", "abca", "=====", "", "───", "€😀€", "", ""}
 
 	for i, str := range testdata {
 		res := GenerateRollingString(str, testlen[i])
@@ -315,6 +915,20 @@ func TestGenerateRollingString(t *testing.T) {
 	}
 }
 
+func BenchmarkGenerateRollingString(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		GenerateRollingString("─", 80)
+	}
+}
+
+func BenchmarkPrintGraphicStringTable(b *testing.B) {
+	ss := strings.Split(strings.Repeat("foo bar baz test123 a ", 50), " ")
+
+	for i := 0; i < b.N; i++ {
+		PrintGraphicStringTable(ss, 5, 1, SingleDoubleLineTable)
+	}
+}
+
 func TestQuoteCLIArgs(t *testing.T) {
 
 	if res := QuoteCLIArgs([]string{"-i"}); res != "-i" {
@@ -750,6 +1364,31 @@ func TestCamelCaseSplit(t *testing.T) {
 		t.Error("Unexpected result:", res)
 		return
 	}
+
+	if res := CamelCaseSplit(""); res != nil {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestCamelCaseSplitFunc(t *testing.T) {
+	testdata := []string{"FooBar", "FooB#ar", "fOObAR", "lower", "foo1bar",
+		"Low\xf2\xe6Er1", "ROCKHard", "myHTTPServer2Go", "A", ""}
+	expected := []string{"[Foo Bar]", "[Foo B # ar]", "[f O Ob AR]", "[lower]", "[foo 1 bar]",
+		"[Low\xf2\xe6Er1]", "[ROCK Hard]", "[my HTTP Server 2 Go]", "[A]", "[]"}
+
+	for i, src := range testdata {
+		var words []string
+
+		CamelCaseSplitFunc(src, func(word string) {
+			words = append(words, word)
+		})
+
+		if res := fmt.Sprint(words); res != expected[i] {
+			t.Error("Unexpected result for", src, ":", res, "expected:", expected[i])
+			return
+		}
+	}
 }
 
 func TestChunkSplit(t *testing.T) {
@@ -798,4 +1437,60 @@ Lor`[1:] {
 		t.Errorf("Unexpected result:\n===============\n#%v#", res)
 		return
 	}
+
+	// Whitespace at the end of a full chunk
+
+	if res := fmt.Sprintf("%q", ChunkSplit("abc def ghi", 4, true)); res != `["abc " "def " "ghi"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprintf("%q", ChunkSplit("äöü€ ab", 5, true)); res != `["äöü€ " "ab"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestChunkSplitBytes(t *testing.T) {
+
+	if res := fmt.Sprint(ChunkSplitBytes("Foobar tester fooooo", 4)); res != "[Foob ar t este r fo oooo]" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// "€" has 3 bytes and "😀" has 4 bytes - naive byte slicing would split them
+
+	if res := fmt.Sprintf("%q", ChunkSplitBytes("a€b€😀c", 4)); res != `["a€" "b€" "😀" "c"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprintf("%q", ChunkSplitBytes("€€€", 5)); res != `["€" "€" "€"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// A single rune which is larger than the chunk size is not split
+
+	if res := fmt.Sprintf("%q", ChunkSplitBytes("😀😀a", 2)); res != `["😀" "😀" "a"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprintf("%q", ChunkSplitBytes("abc", 3)); res != `["abc"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := fmt.Sprintf("%q", ChunkSplitBytes("abc", 0)); res != `["abc"]` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	for _, chunk := range ChunkSplitBytes(strings.Repeat("ä€😀x", 50), 7) {
+		if !utf8.ValidString(chunk) || len(chunk) > 7 {
+			t.Error("Unexpected chunk:", chunk)
+			return
+		}
+	}
 }
@@ -17,6 +17,7 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 var cSyleCommentsRegexp = regexp.MustCompile("(?s)//.*?\n|/\\*.*?\\*/")
@@ -28,6 +29,157 @@ func StripCStyleComments(text []byte) []byte {
 	return cSyleCommentsRegexp.ReplaceAll(text, nil)
 }
 
+/*
+CommentStyle is a set of comment styles which should be recognized.
+*/
+type CommentStyle int
+
+/*
+Available comment styles
+*/
+const (
+	HashComments   CommentStyle = 1 << iota // Line comments starting with #
+	SlashComments                           // Line comments starting with //
+	BlockComments                           // Block comments between /* and */
+	CStyleComments = SlashComments | BlockComments
+)
+
+/*
+StripComments strips out comments of the given styles from a given text.
+Comment markers inside string literals (enclosed in single quotes, double
+quotes or backticks) are ignored. Line comments are removed up to the end of
+the line - the newline character is kept.
+*/
+func StripComments(text []byte, style CommentStyle) []byte {
+	var ret []byte
+	var quote byte
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		if quote != 0 {
+
+			// Copy string literals as they are
+
+			ret = append(ret, c)
+
+			if c == '\\' && quote != '`' && i+1 < len(text) {
+				i++
+				ret = append(ret, text[i])
+			} else if c == quote {
+				quote = 0
+			}
+
+			continue
+		}
+
+		hasNext := i+1 < len(text)
+
+		if (style&HashComments != 0 && c == '#') ||
+			(style&SlashComments != 0 && c == '/' && hasNext && text[i+1] == '/') {
+
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+			i-- // Keep the newline
+
+		} else if style&BlockComments != 0 && c == '/' && hasNext && text[i+1] == '*' {
+
+			if end := bytes.Index(text[i+2:], []byte("*/")); end != -1 {
+				i += end + 3
+			} else {
+				i = len(text)
+			}
+
+		} else {
+
+			if c == '"' || c == '\'' || c == '`' {
+				quote = c
+			}
+
+			ret = append(ret, c)
+		}
+	}
+
+	return ret
+}
+
+var placeholderRegexp = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+/*
+ExpandPlaceholders replaces all ${name} placeholders in a given string with
+the corresponding values from a given map. Placeholders without a value are
+left as they are and their names are returned in a list. A placeholder can be
+escaped by using $${name} which results in the literal ${name}.
+*/
+func ExpandPlaceholders(s string, vars map[string]string) (string, []string) {
+	var missing []string
+
+	res := placeholderRegexp.ReplaceAllStringFunc(s, func(match string) string {
+
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+
+		name := match[2 : len(match)-1]
+
+		if val, ok := vars[name]; ok {
+			return val
+		}
+
+		if IndexOf(name, missing) == -1 {
+			missing = append(missing, name)
+		}
+
+		return match
+	})
+
+	return res, missing
+}
+
+/*
+WordWrap wraps a given string into lines which are at most width runes long.
+Lines are broken at whitespace; words which are longer than the width are
+split. Existing newlines are kept and whitespace between words is collapsed
+into a single space. A width of 0 or less returns the string unchanged.
+*/
+func WordWrap(s string, width int) string {
+	var lines []string
+
+	if width <= 0 {
+		return s
+	}
+
+	for _, paragraph := range strings.Split(s, "\n") {
+		var line []rune
+
+		for _, word := range strings.Fields(paragraph) {
+			w := []rune(word)
+
+			if len(line) > 0 && len(line)+1+len(w) > width {
+				lines = append(lines, string(line))
+				line = nil
+			}
+
+			// Split words which do not fit into a line
+
+			for len(w) > width {
+				lines = append(lines, string(w[:width]))
+				w = w[width:]
+			}
+
+			if len(line) > 0 {
+				line = append(line, ' ')
+			}
+			line = append(line, w...)
+		}
+
+		lines = append(lines, string(line))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 /*
 CreateDisplayString changes all "_" characters into spaces and properly capitalizes
 the resulting string.
@@ -42,23 +194,23 @@ func CreateDisplayString(str string) string {
 
 // The following words should not be capitalized
 //
-var notCapitalize = map[string]string{
-	"a":    "",
-	"an":   "",
-	"and":  "",
-	"at":   "",
-	"but":  "",
-	"by":   "",
-	"for":  "",
-	"from": "",
-	"in":   "",
-	"nor":  "",
-	"on":   "",
-	"of":   "",
-	"or":   "",
-	"the":  "",
-	"to":   "",
-	"with": "",
+var notCapitalize = map[string]struct{}{
+	"a":    {},
+	"an":   {},
+	"and":  {},
+	"at":   {},
+	"but":  {},
+	"by":   {},
+	"for":  {},
+	"from": {},
+	"in":   {},
+	"nor":  {},
+	"on":   {},
+	"of":   {},
+	"or":   {},
+	"the":  {},
+	"to":   {},
+	"with": {},
 }
 
 /*
@@ -68,11 +220,20 @@ conjunctions: and, but, or, for, nor; prepositions (fewer than five
 letters): on, at, to, from, by.
 */
 func ProperTitle(input string) string {
+	return ProperTitleWith(input, notCapitalize)
+}
+
+/*
+ProperTitleWith will properly capitalize a title string like ProperTitle but
+uses a given set of lowercase words which are not capitalized. The words of
+the set must be lowercase. The first and the last word are always capitalized.
+*/
+func ProperTitleWith(input string, lowercaseWords map[string]struct{}) string {
 	words := strings.Fields(strings.ToLower(input))
 	size := len(words)
 
 	for index, word := range words {
-		if _, ok := notCapitalize[word]; !ok || index == 0 || index == size-1 {
+		if _, ok := lowercaseWords[word]; !ok || index == 0 || index == size-1 {
 			words[index] = strings.Title(word)
 		}
 	}
@@ -80,6 +241,60 @@ func ProperTitle(input string) string {
 	return strings.Join(words, " ")
 }
 
+/*
+TitleCasePreserveAcronyms will properly capitalize a title string like
+ProperTitle but keeps acronyms. Words which match one of the given acronyms
+(ignoring case and punctuation) are written as in the list. Words
+which are already all uppercase and have between two and five letters are
+kept as they are.
+*/
+func TitleCasePreserveAcronyms(s string, acronyms []string) string {
+	words := strings.Fields(s)
+	size := len(words)
+
+	isPunctuation := func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+
+	for index, word := range words {
+		// Match only the first sequence of letters and digits (e.g. nasa in "nasa's")
+
+		core := strings.TrimLeftFunc(word, isPunctuation)
+		if i := strings.IndexFunc(core, isPunctuation); i != -1 {
+			core = core[:i]
+		}
+
+		isAcronym := false
+
+		for _, acronym := range acronyms {
+			if core != "" && strings.EqualFold(core, acronym) {
+				words[index] = strings.Replace(word, core, acronym, 1)
+				isAcronym = true
+				break
+			}
+		}
+
+		if isAcronym {
+			continue
+		}
+
+		if l := utf8.RuneCountInString(core); l >= 2 && l <= 5 && core == strings.ToUpper(core) &&
+			core != strings.ToLower(core) {
+			continue
+		}
+
+		word = strings.ToLower(word)
+
+		if _, ok := notCapitalize[word]; !ok || index == 0 || index == size-1 {
+			word = strings.Title(word)
+		}
+
+		words[index] = word
+	}
+
+	return strings.Join(words, " ")
+}
+
 /*
 ToUnixNewlines converts all newlines in a given string to unix newlines.
 */
@@ -88,6 +303,50 @@ func ToUnixNewlines(s string) string {
 	return strings.Replace(s, "\r", "\n", -1)
 }
 
+/*
+SplitLines splits a given string into lines. Unix (\n), Windows (\r\n) and
+old Mac (\r) line endings are recognised. A single trailing line ending does
+not produce an empty last line.
+*/
+func SplitLines(s string) []string {
+	return SplitLinesWithOptions(s, false)
+}
+
+/*
+SplitLinesWithOptions splits a given string into lines (see SplitLines). If
+keepLineEndings is set then each line keeps its original line ending.
+*/
+func SplitLinesWithOptions(s string, keepLineEndings bool) []string {
+	var lines []string
+
+	if !keepLineEndings {
+		if s = ToUnixNewlines(s); s == "" {
+			return lines
+		}
+		return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	}
+
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\r' || s[i] == '\n' {
+
+			if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+
+			lines = append(lines, s[start:i+1])
+			start = i + 1
+		}
+	}
+
+	if start < len(s) {
+		lines = append(lines, s[start:])
+	}
+
+	return lines
+}
+
 /*
 TrimBlankLines removes blank initial and trailing lines.
 */
@@ -96,9 +355,47 @@ func TrimBlankLines(s string) string {
 }
 
 /*
-StripUniformIndentation removes uniform indentation from a string.
+NormalizeWhitespace collapses all runs of whitespace (including newlines) in
+a given string into a single space and removes leading and trailing
+whitespace.
+*/
+func NormalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+/*
+NormalizeWhitespaceKeepNewlines collapses all runs of whitespace within each
+line of a given string into a single space and removes leading and trailing
+whitespace of each line. Newlines are kept.
+*/
+func NormalizeWhitespaceKeepNewlines(s string) string {
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+		lines[i] = NormalizeWhitespace(line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+/*
+StripUniformIndentation removes uniform indentation from a string. Lines which
+contain only whitespace are returned as empty lines.
 */
 func StripUniformIndentation(s string) string {
+	return StripUniformIndentationWithOptions(s, false)
+}
+
+/*
+StripUniformIndentationWithOptions removes uniform indentation from a string.
+Lines which contain only whitespace are returned as empty lines unless
+keepTrailingBlankLines is set - then the whitespace of all blank lines after
+the last non-blank line is kept (minus the uniform indentation). This keeps
+the original blank-line structure of e.g. block strings. Note that
+TrimBlankLines only removes newline characters - it removes the emptied
+trailing lines of the default mode but stops at kept whitespace.
+*/
+func StripUniformIndentationWithOptions(s string, keepTrailingBlankLines bool) string {
 	leadingWhitespace := func(line string) int {
 		var count int
 
@@ -119,17 +416,23 @@ func StripUniformIndentation(s string) string {
 	// empty lines
 
 	minCount := math.MaxInt16
+	lastContentLine := -1
 	reader := strings.NewReader(s)
 	scanner := bufio.NewScanner(reader)
 
-	for scanner.Scan() {
+	for lineNo := 0; scanner.Scan(); lineNo++ {
 		if lw := leadingWhitespace(scanner.Text()); lw != -1 {
 			if lw < minCount {
 				minCount = lw
 			}
+			lastContentLine = lineNo
 		}
 	}
 
+	if lastContentLine == -1 {
+		minCount = 0 // String contains only whitespace
+	}
+
 	// Go through the string again and build up the output
 
 	var buf bytes.Buffer
@@ -137,10 +440,10 @@ func StripUniformIndentation(s string) string {
 	reader.Seek(0, io.SeekStart)
 	scanner = bufio.NewScanner(reader)
 
-	for scanner.Scan() {
+	for lineNo := 0; scanner.Scan(); lineNo++ {
 		line := scanner.Text()
 
-		if strings.TrimSpace(line) != "" {
+		if strings.TrimSpace(line) != "" || (keepTrailingBlankLines && lineNo > lastContentLine) {
 			for i, r := range line {
 				if i >= minCount {
 					buf.WriteRune(r)
@@ -155,9 +458,137 @@ func StripUniformIndentation(s string) string {
 
 	ret := buf.String()
 
-	if !strings.HasSuffix(s, "\n") {
+	if !strings.HasSuffix(s, "\n") && len(ret) > 0 {
 		ret = ret[:len(ret)-1]
 	}
 
 	return ret
 }
+
+/*
+DetectIndentation inspects the leading whitespace of all lines in a given
+string. Returns the dominant indentation unit ("\t", " " or an empty string
+if no line is indented) and a flag if tabs and spaces are mixed.
+*/
+func DetectIndentation(s string) (string, bool) {
+	var tabLines, spaceLines int
+	var mixed bool
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+
+	for scanner.Scan() {
+		var hasTab, hasSpace bool
+
+		line := scanner.Text()
+
+		if strings.TrimSpace(line) == "" {
+			continue // Ignore lines which are full of whitespace
+		}
+
+		for _, r := range line {
+			if r == '\t' {
+				hasTab = true
+			} else if r == ' ' {
+				hasSpace = true
+			} else {
+				break
+			}
+		}
+
+		if hasTab {
+			tabLines++
+		}
+		if hasSpace {
+			spaceLines++
+		}
+
+		mixed = mixed || (hasTab && hasSpace)
+	}
+
+	mixed = mixed || (tabLines > 0 && spaceLines > 0)
+
+	if tabLines == 0 && spaceLines == 0 {
+		return "", false
+	} else if tabLines > spaceLines {
+		return "\t", mixed
+	}
+
+	return " ", mixed
+}
+
+// The following abbreviations do not end a sentence
+//
+var sentenceAbbreviations = map[string]struct{}{
+	"dr.":   {},
+	"e.g.":  {},
+	"etc.":  {},
+	"i.e.":  {},
+	"inc.":  {},
+	"jr.":   {},
+	"ltd.":  {},
+	"mr.":   {},
+	"mrs.":  {},
+	"ms.":   {},
+	"no.":   {},
+	"prof.": {},
+	"sr.":   {},
+	"st.":   {},
+	"vs.":   {},
+}
+
+/*
+SplitSentences splits a given text into sentences. A sentence ends with ".", "!"
+or "?" followed by whitespace and a capital letter. Common abbreviations like
+"Mr." or "e.g." and decimal numbers like "3.14" do not end a sentence. This is
+a rule-based best-effort approach - e.g. a sentence which ends with "etc." is
+not split from the following sentence.
+*/
+func SplitSentences(s string) []string {
+	var res []string
+
+	runes := []rune(s)
+	start := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+
+		// Look for whitespace followed by a capital letter
+
+		j := i + 1
+		for j < len(runes) && unicode.IsSpace(runes[j]) {
+			j++
+		}
+
+		if j == i+1 || j == len(runes) || !unicode.IsUpper(runes[j]) {
+			continue
+		}
+
+		if r == '.' {
+
+			// Check the word before the full stop for known abbreviations
+
+			k := i
+			for k > start && !unicode.IsSpace(runes[k-1]) {
+				k--
+			}
+
+			if _, ok := sentenceAbbreviations[strings.ToLower(string(runes[k:i+1]))]; ok {
+				continue
+			}
+		}
+
+		res = append(res, strings.TrimSpace(string(runes[start:i+1])))
+		start = j
+		i = j - 1
+	}
+
+	if last := strings.TrimSpace(string(runes[start:])); last != "" {
+		res = append(res, last)
+	}
+
+	return res
+}
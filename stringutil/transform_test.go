@@ -10,6 +10,9 @@
 package stringutil
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -33,6 +36,69 @@ This is a test
 	}
 }
 
+func TestStripComments(t *testing.T) {
+
+	test := `url := "http://example.com" // The URL
+/* Block
+comment */ path := '/*not a comment*/' + ` + "`//raw`" + `
+esc := "say \"// hi\"" # hash
+# Hash comment
+last // no newline`
+
+	if res := string(StripComments([]byte(test), CStyleComments)); res != `url := "http://example.com" 
+ path := '/*not a comment*/' + `+"`//raw`"+`
+esc := "say \"// hi\"" # hash
+# Hash comment
+last ` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := string(StripComments([]byte(test), HashComments)); res != `url := "http://example.com" // The URL
+/* Block
+comment */ path := '/*not a comment*/' + `+"`//raw`"+`
+esc := "say \"// hi\"" 
+
+last // no newline` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := string(StripComments([]byte("a /* unterminated"), BlockComments)); res != "a " {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := string(StripComments([]byte(`"unterminated // string`), CStyleComments)); res != `"unterminated // string` {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestExpandPlaceholders(t *testing.T) {
+	vars := map[string]string{
+		"name": "foo",
+		"id":   "123",
+	}
+
+	if res, missing := ExpandPlaceholders("Hello ${name} (${id})", vars); res != "Hello foo (123)" || missing != nil {
+		t.Error("Unexpected result:", res, missing)
+		return
+	}
+
+	if res, missing := ExpandPlaceholders("${nme} ${name} ${x}${nme}", vars); res != "${nme} foo ${x}${nme}" ||
+		fmt.Sprint(missing) != "[nme x]" {
+		t.Error("Unexpected result:", res, missing)
+		return
+	}
+
+	if res, missing := ExpandPlaceholders("$${name} is ${name} $${literal}", vars); res != "${name} is foo ${literal}" ||
+		missing != nil {
+		t.Error("Unexpected result:", res, missing)
+		return
+	}
+}
+
 func TestCreateDisplayString(t *testing.T) {
 	testdata := []string{"this is a tEST", "_bla", "a_bla", "a__bla", "a__b_la", "",
 		"a fool a to be to"}
@@ -48,6 +114,70 @@ func TestCreateDisplayString(t *testing.T) {
 	}
 }
 
+func TestProperTitleWith(t *testing.T) {
+	german := map[string]struct{}{
+		"der": {}, "die": {}, "das": {}, "und": {}, "von": {}, "im": {},
+	}
+
+	if res := ProperTitleWith("der herr DER ringe und die rückkehr des königs", german); res !=
+		"Der Herr der Ringe und die Rückkehr Des Königs" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	// First and last words are always capitalized
+
+	if res := ProperTitleWith("die katze im haus von der", german); res != "Die Katze im Haus von Der" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ProperTitleWith("the lord of the rings", nil); res != "The Lord Of The Rings" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := ProperTitleWith("the lord of the rings", notCapitalize); res != ProperTitle("the lord of the rings") ||
+		res != "The Lord of the Rings" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
+func TestTitleCasePreserveAcronyms(t *testing.T) {
+	acronyms := []string{"NASA", "HTTP", "GraphQL"}
+
+	testdata := []string{
+		"the nasa report",
+		"a guide to http and graphql",
+		"nasa's report on the ISS",
+		"why the FBI and CIA of the USA care",
+		"a LOOOONG word and an A",
+		"",
+	}
+	expected := []string{
+		"The NASA Report",
+		"A Guide to HTTP and GraphQL",
+		"NASA's Report on the ISS",
+		"Why the FBI and CIA of the USA Care",
+		"A Loooong Word and an A",
+		"",
+	}
+
+	for i, str := range testdata {
+		if res := TitleCasePreserveAcronyms(str, acronyms); res != expected[i] {
+			t.Error("Unexpected result:", res, "expected:", expected[i])
+			return
+		}
+	}
+
+	if res := TitleCasePreserveAcronyms("the nasa report", nil); res != "The Nasa Report" ||
+		res != ProperTitle("the nasa report") {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
+
 func TestStripUniformIndentation(t *testing.T) {
 
 	testdata := []string{`
@@ -94,6 +224,68 @@ ccc
 	}
 }
 
+func TestStripUniformIndentationWithOptions(t *testing.T) {
+	input := "\n    foo\n      bar\n    \n        \n  \n"
+
+	if res := StripUniformIndentationWithOptions(input, false); res != "\nfoo\n  bar\n\n\n\n" ||
+		res != StripUniformIndentation(input) {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	if res := StripUniformIndentationWithOptions(input, true); res != "\nfoo\n  bar\n\n    \n\n" {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	// Blank lines between content lines are always emptied
+
+	if res := StripUniformIndentationWithOptions("  a\n      \n  b\n      ", true); res != "a\n\nb\n    " {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	// TrimBlankLines stops at kept whitespace
+
+	if res := TrimBlankLines(StripUniformIndentationWithOptions(input, false)); res != "foo\n  bar" {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	if res := TrimBlankLines(StripUniformIndentationWithOptions(input, true)); res != "foo\n  bar\n\n    " {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	if res := StripUniformIndentationWithOptions("  \n\t\n", true); res != "  \n\t\n" {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+
+	if res := StripUniformIndentationWithOptions("", true); res != "" {
+		t.Error("Unexpected result:", strconv.Quote(res))
+		return
+	}
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	testdata := []string{"", " \t\n ", "a  b", "\tfoo\t\tbar  baz\n", "a \t\n\r\n b", "ä\u00a0\u3000ö  ü"}
+	expected := []string{"", "", "a b", "foo bar baz", "a b", "ä ö ü"}
+	expectedKeep := []string{"", "\n", "a b", "foo bar baz\n", "a\n\nb", "ä ö ü"}
+
+	for i, str := range testdata {
+		if res := NormalizeWhitespace(str); res != expected[i] {
+			t.Error("Unexpected result:", strconv.Quote(str), strconv.Quote(res), "expected:", expected[i])
+			return
+		}
+
+		if res := NormalizeWhitespaceKeepNewlines(str); res != expectedKeep[i] {
+			t.Error("Unexpected result:", strconv.Quote(str), strconv.Quote(res), "expected:", expectedKeep[i])
+			return
+		}
+	}
+}
+
 func TestNewLineTransform(t *testing.T) {
 	res := TrimBlankLines(ToUnixNewlines("\r\n  test123\r\ntest123\r\n"))
 	if res != "  test123\ntest123" {
@@ -101,3 +293,123 @@ func TestNewLineTransform(t *testing.T) {
 		return
 	}
 }
+
+func TestSplitLines(t *testing.T) {
+	testdata := []string{"", "a", "a\n", "a\n\n", "\n", "a\r\nb\rc\nd", "a\r\n\r\nb\r", "a\n\rb"}
+	expected := []string{"[]", "[a]", "[a]", `["a" ""]`, `[""]`, "[a b c d]", `["a" "" "b"]`, `["a" "" "b"]`}
+	expectedKeep := []string{"[]", "[a]", `["a\n"]`, `["a\n" "\n"]`, `["\n"]`, `["a\r\n" "b\r" "c\n" "d"]`,
+		`["a\r\n" "\r\n" "b\r"]`, `["a\n" "\r" "b"]`}
+
+	format := func(lines []string) string {
+		for _, l := range lines {
+			if l == "" || strings.ContainsAny(l, "\r\n") {
+				return fmt.Sprintf("%q", lines)
+			}
+		}
+		return fmt.Sprint(lines)
+	}
+
+	for i, str := range testdata {
+		if res := format(SplitLines(str)); res != expected[i] {
+			t.Error("Unexpected result:", strconv.Quote(str), res, "expected:", expected[i])
+			return
+		}
+
+		if res := format(SplitLinesWithOptions(str, true)); res != expectedKeep[i] {
+			t.Error("Unexpected result:", strconv.Quote(str), res, "expected:", expectedKeep[i])
+			return
+		}
+
+		if strings.Join(SplitLinesWithOptions(str, true), "") != str {
+			t.Error("Lines with line endings should join to the original string:", strconv.Quote(str))
+			return
+		}
+	}
+}
+
+func TestDetectIndentation(t *testing.T) {
+
+	if unit, mixed := DetectIndentation("foo\n  bar\n    baz\n"); unit != " " || mixed {
+		t.Error("Unexpected result:", unit, mixed)
+		return
+	}
+
+	if unit, mixed := DetectIndentation("foo\n\tbar\n\t\tbaz\n  \n"); unit != "\t" || mixed {
+		t.Error("Unexpected result:", unit, mixed)
+		return
+	}
+
+	if unit, mixed := DetectIndentation("foo\n\tbar\n\t\tbaz\n  baz\n"); unit != "\t" || !mixed {
+		t.Error("Unexpected result:", unit, mixed)
+		return
+	}
+
+	if unit, mixed := DetectIndentation("foo\n \tbar\n  baz\n"); unit != " " || !mixed {
+		t.Error("Unexpected result:", unit, mixed)
+		return
+	}
+
+	if unit, mixed := DetectIndentation("foo\nbar"); unit != "" || mixed {
+		t.Error("Unexpected result:", unit, mixed)
+		return
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+
+	res := SplitSentences("Mr. Smith paid 3.14 dollars. Was it enough? " +
+		"Yes! Fruit e.g. Apples are cheap.  Dr. Who agrees.")
+
+	if fmt.Sprint(len(res), res) != "5 [Mr. Smith paid 3.14 dollars. Was it enough? "+
+		"Yes! Fruit e.g. Apples are cheap. Dr. Who agrees.]" {
+		t.Error("Unexpected result:", len(res), res)
+		return
+	}
+
+	if res := SplitSentences("no capital. after this"); fmt.Sprint(len(res), res) != "1 [no capital. after this]" {
+		t.Error("Unexpected result:", len(res), res)
+		return
+	}
+
+	if res := SplitSentences("  "); len(res) != 0 {
+		t.Error("Unexpected result:", len(res), res)
+		return
+	}
+}
+
+func TestWordWrap(t *testing.T) {
+
+	if res := WordWrap("The quick brown fox jumps over the lazy dog", 10); res != `
+The quick
+brown fox
+jumps over
+the lazy
+dog`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := WordWrap("a verylongwordwhichdoesnotfit b\n\nnext  paragraph", 8); res != `
+a
+verylong
+wordwhic
+hdoesnot
+fit b
+
+next
+paragrap
+h`[1:] {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := WordWrap("äöü äöü", 3); res != "äöü\näöü" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+
+	if res := WordWrap("foo  bar", 0); res != "foo  bar" {
+		t.Error("Unexpected result:", res)
+		return
+	}
+}
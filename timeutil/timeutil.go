@@ -12,7 +12,10 @@ package timeutil
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/krotik/common/stringutil"
 )
 
 /*
@@ -68,3 +71,127 @@ func TimestampString(ts, loc string) (string, error) {
 
 	return tsTime.In(l).String(), nil
 }
+
+/*
+durationUnits are the units which are used to humanize durations.
+*/
+var durationUnits = []struct {
+	unit  time.Duration
+	short string
+	long  string
+}{
+	{24 * time.Hour, "d", "day"},
+	{time.Hour, "h", "hour"},
+	{time.Minute, "m", "minute"},
+	{time.Second, "s", "second"},
+	{time.Millisecond, "ms", "millisecond"},
+	{time.Microsecond, "µs", "microsecond"},
+	{time.Nanosecond, "ns", "nanosecond"},
+}
+
+/*
+HumanizeDuration returns a compact human readable representation of a given
+duration (e.g. 500ms, 2h3m, 1d4h). Unlike time.Duration.String() it supports
+days and omits zero units.
+*/
+func HumanizeDuration(d time.Duration) string {
+	return humanizeDuration(d, false)
+}
+
+/*
+HumanizeDurationLong returns a human readable representation of a given
+duration using words (e.g. 2 hours 3 minutes).
+*/
+func HumanizeDurationLong(d time.Duration) string {
+	return humanizeDuration(d, true)
+}
+
+/*
+humanizeDuration produces a human readable representation of a duration.
+*/
+func humanizeDuration(d time.Duration, long bool) string {
+	var parts []string
+
+	if d == 0 {
+		if long {
+			return "0 seconds"
+		}
+		return "0s"
+	}
+
+	sign := ""
+
+	// Use uint64 to be able to represent the absolute value of the
+	// smallest possible duration
+
+	ud := uint64(d)
+	if d < 0 {
+		sign = "-"
+		ud = uint64(-d)
+	}
+
+	for _, u := range durationUnits {
+		count := ud / uint64(u.unit)
+		ud %= uint64(u.unit)
+
+		if count == 0 {
+			continue
+		}
+
+		if long {
+			parts = append(parts, fmt.Sprintf("%v %v%v", count, u.long, stringutil.Plural(int(count))))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v%v", count, u.short))
+		}
+	}
+
+	if long {
+		return sign + strings.Join(parts, " ")
+	}
+
+	return sign + strings.Join(parts, "")
+}
+
+/*
+timeAgoBuckets are the coarse units which are used by TimeAgo.
+*/
+var timeAgoBuckets = []struct {
+	unit time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{7 * 24 * time.Hour, "week"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+}
+
+/*
+TimeAgo returns a human readable description of a given time relative to
+a given current time (e.g. just now, 5 minutes ago, in 3 days). Differences
+are rounded down to the largest fitting unit. Months and years are
+approximated with 30 and 365 days.
+*/
+func TimeAgo(t, now time.Time) string {
+	diff := now.Sub(t)
+
+	future := diff < 0
+	if future {
+		diff = -diff
+	}
+
+	for _, b := range timeAgoBuckets {
+		if count := int(diff / b.unit); count > 0 {
+			desc := fmt.Sprintf("%v %v%v", count, b.name, stringutil.Plural(count))
+
+			if future {
+				return "in " + desc
+			}
+
+			return desc + " ago"
+		}
+	}
+
+	return "just now"
+}
@@ -10,6 +10,7 @@
 package timeutil
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"testing"
@@ -78,3 +79,61 @@ func TestTimestamp(t *testing.T) {
 		return
 	}
 }
+
+func TestHumanizeDuration(t *testing.T) {
+	testdata := []time.Duration{
+		0,
+		500 * time.Millisecond,
+		1500 * time.Microsecond,
+		42 * time.Nanosecond,
+		2*time.Hour + 3*time.Minute,
+		time.Minute + 5*time.Second + 250*time.Millisecond,
+		28 * time.Hour,
+		3*24*time.Hour + time.Second,
+		-90 * time.Second,
+		math.MinInt64,
+	}
+	expected := []string{"0s", "500ms", "1ms500µs", "42ns", "2h3m", "1m5s250ms", "1d4h",
+		"3d1s", "-1m30s", "-106751d23h47m16s854ms775µs808ns"}
+	expectedLong := []string{"0 seconds", "500 milliseconds", "1 millisecond 500 microseconds",
+		"42 nanoseconds", "2 hours 3 minutes", "1 minute 5 seconds 250 milliseconds",
+		"1 day 4 hours", "3 days 1 second", "-1 minute 30 seconds",
+		"-106751 days 23 hours 47 minutes 16 seconds 854 milliseconds 775 microseconds 808 nanoseconds"}
+
+	for i, d := range testdata {
+		if res := HumanizeDuration(d); res != expected[i] {
+			t.Error("Unexpected result:", res, "expected:", expected[i])
+		}
+		if res := HumanizeDurationLong(d); res != expectedLong[i] {
+			t.Error("Unexpected result:", res, "expected:", expectedLong[i])
+		}
+	}
+}
+
+func TestTimeAgo(t *testing.T) {
+	now := time.Date(2020, time.June, 15, 12, 0, 0, 0, time.UTC)
+
+	testdata := []time.Duration{
+		0,
+		-59 * time.Second,
+		30 * time.Second,
+		-time.Minute,
+		-5 * time.Minute,
+		-2*time.Hour - 59*time.Minute,
+		-24 * time.Hour,
+		3 * 24 * time.Hour,
+		-14 * 24 * time.Hour,
+		45 * 24 * time.Hour,
+		-400 * 24 * time.Hour,
+		5 * 365 * 24 * time.Hour,
+	}
+	expected := []string{"just now", "just now", "just now", "1 minute ago",
+		"5 minutes ago", "2 hours ago", "1 day ago", "in 3 days", "2 weeks ago",
+		"in 1 month", "1 year ago", "in 5 years"}
+
+	for i, d := range testdata {
+		if res := TimeAgo(now.Add(d), now); res != expected[i] {
+			t.Error("Unexpected result:", res, "expected:", expected[i])
+		}
+	}
+}